@@ -1,9 +1,11 @@
 package core
 
 import (
+	"compress/gzip"
 	"fmt"
 	"github.com/peter-mount/go-build/util"
 	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/changelog"
 	"github.com/peter-mount/go-build/util/makefile/target"
 	"github.com/peter-mount/go-build/util/meta"
 	"github.com/peter-mount/go-kernel/v2/util/walk"
@@ -22,9 +24,10 @@ type Apt struct {
 }
 
 type Config struct {
-	Disable bool    `yaml:"disable"`
-	Package Package `yaml:"package"`
-	Lintian bool    `yaml:"lintian"`
+	Disable   bool    `yaml:"disable"`
+	Package   Package `yaml:"package"`
+	Lintian   bool    `yaml:"lintian"`
+	Changelog bool    `yaml:"changelog"` // Include changelog.Debian.gz generated from git history
 }
 
 type Package struct {
@@ -101,6 +104,10 @@ func (s *Apt) run() error {
 
 	err := s.copyDist()
 
+	if err == nil && s.config.Changelog {
+		err = s.installChangelog()
+	}
+
 	if err == nil {
 		err = s.installControl()
 	}
@@ -135,6 +142,37 @@ func (s *Apt) installControl() error {
 	return err
 }
 
+// installChangelog generates the debian changelog from the git history
+func (s *Apt) installChangelog() error {
+	p := s.config.Package
+	release, err := changelog.New(p.Version)
+	if err != nil {
+		return err
+	}
+
+	fName := filepath.Join(*s.Encoder.Dest, "usr", "share", "doc", p.Name, "changelog.Debian.gz")
+	err = os.MkdirAll(filepath.Dir(fName), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	_, err = gw.Write([]byte(release.Debian(p.Name, p.Version+"-"+p.Release, p.Maintainer)))
+	if err == nil {
+		err = gw.Close()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	return err
+}
+
 func (s *Apt) loadConfig() error {
 	b, err := os.ReadFile("debian.yaml")
 	if err == nil {
@@ -172,7 +210,7 @@ func (s *Apt) dpkg() error {
 	err := util.RunCommand("dpkg", "--build", *s.Encoder.Dest, *s.Apt)
 
 	if err == nil && s.config.Lintian {
-		util.Label("LINTIAN", *s.Apt)
+		util.Label("LINTIAN", "%s", *s.Apt)
 		err = util.RunCommand("lintian", *s.Apt)
 	}
 
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/bench"
+	"github.com/peter-mount/go-kernel/v2/log"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// Files written by the bench command in the build directory
+const (
+	GoBenchOutput  = "bench.txt"         // Output of go test -bench
+	GoBenchCompare = "bench-compare.txt" // Comparison against the baseline
+)
+
+type BenchConfig struct {
+	Pattern   string  `yaml:"pattern"`   // Benchmarks to run, default all
+	BenchTime string  `yaml:"benchtime"` // -benchtime, e.g. 1s or 100x
+	Count     int     `yaml:"count"`     // Number of times to run each benchmark, default 6
+	Baseline  string  `yaml:"baseline"`  // Results to compare against, default bench-baseline.txt
+	Threshold float64 `yaml:"threshold"` // Percentage a benchmark can get worse by before failing, default 5
+	Alpha     float64 `yaml:"alpha"`     // Significance level of a change, default 0.05
+}
+
+// bench runs the benchmarks then compares them against the baseline,
+// failing when any have regressed by more than the threshold
+func (s *Go) bench() error {
+	config, err := loadTestConfig()
+	if err != nil {
+		return err
+	}
+	s.testFlags(config)
+
+	// Benchmarks with the race detector are many times slower so cannot be compared with the baseline
+	if config.Race {
+		util.Label("GO BENCH", "%s", "ignoring race as benchmarks are not run with the race detector")
+		config.Race = false
+	}
+
+	c := config.Bench
+	if *s.BenchTime != "" {
+		c.BenchTime = *s.BenchTime
+	}
+	if *s.BenchCount > 0 {
+		c.Count = *s.BenchCount
+	}
+
+	module, err := s.module()
+	if err != nil {
+		return err
+	}
+
+	groups, err := config.groups(module)
+	if err != nil {
+		return err
+	}
+
+	dest := *s.Encoder.Dest
+	out := filepath.Join(dest, GoBenchOutput)
+	util.Label("GO BENCH", "%s", out)
+
+	var buf bytes.Buffer
+	for _, g := range groups {
+		args := []string{"test", "-run=^$", "-bench=" + c.Pattern, "-benchmem", "-count=" + strconv.Itoa(c.Count)}
+		if c.BenchTime != "" {
+			args = append(args, "-benchtime="+c.BenchTime)
+		}
+		args = append(args, config.args(g.timeout)...)
+
+		cmd := exec.Command("go", append(args, g.packages...)...)
+		cmd.Stdout = io.MultiWriter(&buf, os.Stdout)
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+
+		if log.IsVerbose() {
+			log.Println(cmd.String())
+		}
+
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if *s.BenchSave {
+		util.Label("GO BENCH", "%s", c.Baseline)
+		return os.WriteFile(c.Baseline, buf.Bytes(), 0644)
+	}
+
+	return s.benchCompare(c, out)
+}
+
+// benchCompare compares the results against the baseline, if there is one
+func (s *Go) benchCompare(c BenchConfig, out string) error {
+	baseline, err := bench.ReadFile(c.Baseline)
+	if err != nil {
+		if os.IsNotExist(err) {
+			util.Label("GO BENCH", "no baseline %s", c.Baseline)
+			return nil
+		}
+		return err
+	}
+
+	results, err := bench.ReadFile(out)
+	if err != nil {
+		return err
+	}
+
+	comparisons := bench.Compare(baseline, results, c.Alpha, c.Threshold)
+
+	compare := filepath.Join(*s.Encoder.Dest, GoBenchCompare)
+	if err := writeReport(compare, func(w io.Writer) error {
+		return bench.WriteTable(w, comparisons)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if err := bench.WriteTable(os.Stdout, comparisons); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	if regressions := bench.Regressions(comparisons); len(regressions) > 0 {
+		return fmt.Errorf("%d benchmarks regressed by more than %.1f%%", len(regressions), c.Threshold)
+	}
+	return nil
+}
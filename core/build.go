@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/executor"
+	"github.com/peter-mount/go-build/util/godeps"
 	"github.com/peter-mount/go-build/util/jenkinsfile"
 	"github.com/peter-mount/go-build/util/makefile"
 	"github.com/peter-mount/go-build/util/makefile/target"
@@ -14,26 +16,46 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 )
 
 type Build struct {
 	Encoder          *Encoder          `kernel:"inject"`
+	Go               *Go               `kernel:"inject"`
 	Dest             *string           `kernel:"flag,build,generate build files"`
 	Platforms        *string           `kernel:"flag,build-platform,platform(s) to build"`
 	Dist             *string           `kernel:"flag,dist,distribution destination"`
 	BlockList        *string           `kernel:"flag,block,block list"`
 	BuildNode        *string           `kernel:"flag,build-node,Jenkins node to run on,go"`
 	Parallelize      *bool             `kernel:"flag,build-parallel,parallelize Jenkinsfile"`
+	Declarative      *bool             `kernel:"flag,build-declarative,generate a declarative Jenkinsfile"`
+	GitHub           *bool             `kernel:"flag,build-github,generate GitHub Actions workflow"`
+	GitLabCI         *bool             `kernel:"flag,build-gitlab,generate GitLab CI pipeline"`
+	WoodpeckerCI     *bool             `kernel:"flag,build-woodpecker,generate Woodpecker pipeline"`
 	ArchiveArtifacts *string           `kernel:"flag,build-archiveArtifacts,archive files on completion"`
 	NoTools          *bool             `kernel:"flag,build-no-tools,set if no tools are defined"`
 	BuildLocal       *bool             `kernel:"flag,build-local,Build for local platform only"`
+	Ninja            *string           `kernel:"flag,build-ninja,generate ninja build file"`
+	Taskfile         *string           `kernel:"flag,build-taskfile,generate Taskfile.yml"`
+	Justfile         *string           `kernel:"flag,build-justfile,generate justfile"`
+	RunTargets       *string           `kernel:"flag,run,build target(s) without make"`
+	RunJobs          *int              `kernel:"flag,run-jobs,number of rules -run will run concurrently (0 for number of CPUs)"`
+	RunKeepGoing     *bool             `kernel:"flag,run-keep-going,keep going after a failure with -run"`
 	libProviders     []LibProvider     // Deprecated
 	extensions       Extension         // Extensions to run
 	documentation    DocumentationList // Documentation extensions to run
 	makefile         DocumentationList // Documentation at root level
 	jenkins          JenkinsList       // Jenkins extensions
+	jenkinsPost      JenkinsList       // Jenkins extensions run on completion
+	jenkinsConfig    *JenkinsConfig    // Jenkins configuration from jenkins.yaml
+	gitHubActions    GitHubActionList  // GitHub Actions extensions
+	gitLab           GitLabList        // GitLab CI extensions
+	woodpecker       WoodpeckerList    // Woodpecker extensions
+	completion       makefile.Handler  // Handlers adding the final step of the all target
+	initialise       makefile.Handler  // Handlers adding steps to the init target
+	runCompletion    []RunCompletion   // Handlers run once -run has completed
 	cleanDirectories sort.StringSlice  // Directories to clean other than builds and dist
 	buildArch        arch.Arch         // The build platform architecture
 }
@@ -58,6 +80,27 @@ func (s *Build) AddCleanDirectory(dir string) {
 	s.cleanDirectories.Sort()
 }
 
+// AddCompletion adds a handler which adds lines to the all target.
+// These are run once every platform has been built.
+func (s *Build) AddCompletion(h makefile.Handler) {
+	s.completion = s.completion.Then(h)
+}
+
+// AddInit adds a handler which adds lines to the init target.
+// These are run at the start of every make run which builds a platform or runs the tests.
+func (s *Build) AddInit(h makefile.Handler) {
+	s.initialise = s.initialise.Then(h)
+}
+
+// RunCompletion is called once -run has completed, successful or not
+type RunCompletion func(summary *executor.Summary) error
+
+// AddRunCompletion adds a handler called once -run has completed.
+// Unlike AddCompletion this is also called when the build failed.
+func (s *Build) AddRunCompletion(h RunCompletion) {
+	s.runCompletion = append(s.runCompletion, h)
+}
+
 // BuildArch returns the arch.Arch the build is running under
 func (s *Build) BuildArch() arch.Arch {
 	return s.buildArch
@@ -92,7 +135,7 @@ func (s *Build) Run() error {
 		log.Println(version.Version)
 	}
 
-	if *s.Dest != "" {
+	if *s.Dest != "" || *s.Ninja != "" || *s.Taskfile != "" || *s.Justfile != "" || *s.RunTargets != "" {
 		meta, err := meta.New()
 		if err != nil {
 			return err
@@ -108,21 +151,61 @@ func (s *Build) Run() error {
 			return err
 		}
 
-		err = s.generate(tools, arch, meta)
+		builder, err := s.makefileBuilder(tools, arch, meta)
 		if err != nil {
 			return err
 		}
 
-		err = s.platformIndex(arch)
-		if err != nil {
+		if *s.Dest != "" {
+			err = s.writeMakefile(builder)
+			if err == nil {
+				err = s.generateProjectFiles(arch)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := s.writeRunners(builder); err != nil {
 			return err
 		}
 
-		return s.jenkinsfile(arch)
+		if *s.RunTargets != "" {
+			return s.run(builder)
+		}
 	}
 	return nil
 }
 
+// RegenerateProjectFiles regenerates the files committed with a project,
+// e.g. platforms.md and Jenkinsfile, without generating the Makefile
+func (s *Build) RegenerateProjectFiles() error {
+	arches, err := arch.GetArches()
+	if err != nil {
+		return err
+	}
+	return s.generateProjectFiles(arches)
+}
+
+func (s *Build) generateProjectFiles(arches []arch.Arch) error {
+	err := s.platformIndex(arches)
+	if err != nil {
+		return err
+	}
+
+	err = s.jenkinsfile(arches)
+	if err == nil && *s.GitHub {
+		err = s.gitHubWorkflow(arches)
+	}
+	if err == nil && *s.GitLabCI {
+		err = s.gitLabPipeline(arches)
+	}
+	if err == nil && *s.WoodpeckerCI {
+		err = s.woodpeckerPipeline(arches)
+	}
+	return err
+}
+
 func (s *Build) getTools() ([]string, error) {
 	var tools []string
 
@@ -152,7 +235,8 @@ func (s *Build) getTools() ([]string, error) {
 	return tools, nil
 }
 
-func (s *Build) generate(tools []string, arches []arch.Arch, meta *meta.Meta) error {
+// makefileBuilder returns the rules to build the project
+func (s *Build) makefileBuilder(tools []string, arches []arch.Arch, meta *meta.Meta) (makefile.Builder, error) {
 
 	builder := makefile.New()
 	builder.Comment("Generated Makefile %s", meta.Time).
@@ -161,11 +245,16 @@ func (s *Build) generate(tools []string, arches []arch.Arch, meta *meta.Meta) er
 		SetVar("export BUILD_TIME", "%q", meta.Time).
 		SetVar("export BUILD_PACKAGE_NAME", "%q", meta.PackageName).
 		SetVar("export BUILD_PACKAGE_PREFIX", "%q", meta.PackagePrefix).
-		Phony("all", "clean", "init", "test")
+		Phony("all", "clean", "init", "test", "test-race", "test-short", "test-cross", "integration", "bench", "fuzz")
+
+	if err := s.toolSources(tools, builder); err != nil {
+		return nil, err
+	}
 
 	s.init(builder)
 	s.clean(builder)
-	s.test(builder)
+	s.test(builder, arches)
+	s.moduleHash(tools, builder)
 
 	root, platforms := s.allRule(arches, builder)
 	allPlatforms := len(platforms) == 0
@@ -211,6 +300,8 @@ func (s *Build) generate(tools []string, arches []arch.Arch, meta *meta.Meta) er
 		}
 	}
 
+	s.integration(builder, arches, allPlatforms || platforms[s.buildArch.Target()])
+
 	// BuildLocal then all should start with the build platform only
 	if *s.BuildLocal {
 		root.RemoveDependencies().
@@ -223,17 +314,28 @@ func (s *Build) generate(tools []string, arches []arch.Arch, meta *meta.Meta) er
 	// Any custom makefile entries
 	s.addDocumentation("", s.makefile, builder, meta)
 
-	if err := os.MkdirAll(filepath.Dir(*s.Dest), 0755); err != nil {
+	return builder, nil
+}
+
+func (s *Build) writeMakefile(builder makefile.Builder) error {
+	return writeFile(*s.Dest, builder.Build())
+}
+
+func writeFile(name, content string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(*s.Dest, []byte(builder.Build()), 0644)
+	return os.WriteFile(name, []byte(content), 0644)
 }
 
 func (s *Build) allRule(arches []arch.Arch, builder makefile.Builder) (makefile.Builder, map[string]bool) {
 	all := builder.Rule("all")
 	platforms := make(map[string]bool)
 
+	// Add the final steps now so they are rendered directly under the rule
+	_ = s.completion.Do(all)
+
 	// Generate all target with either all or subset of platforms
 	if *s.Platforms != "" {
 		plats := strings.Split(*s.Platforms, " ")
@@ -299,8 +401,9 @@ func (s *Build) targetGroups(arches []arch.Arch, builder makefile.Builder, platf
 }
 
 func (s *Build) init(builder makefile.Builder) {
-	builder.Rule("init").
+	rule := builder.Rule("init").
 		Mkdir(*s.Encoder.Dest, *s.Dist)
+	_ = s.initialise.Do(rule)
 }
 
 func (s *Build) callBuilder(builder makefile.Builder, action, cmd string, args ...string) {
@@ -313,20 +416,113 @@ func (s *Build) clean(builder makefile.Builder) {
 	s.callBuilder(rule, "go", "clean", "--", "-testcache")
 }
 
-func (s *Build) test(builder makefile.Builder) {
+func (s *Build) test(builder makefile.Builder, arches []arch.Arch) {
 	out := filepath.Join(*s.Encoder.Dest, "go-text.txt")
 
 	rule := builder.Rule("test", "init").
 		Mkdir(filepath.Dir(out))
 
-	s.callBuilder(rule, "go", "test")
+	// Pass on -go-test-fail so the generated test rule fails on test failures
+	var args []string
+	if *s.Go.FailTests {
+		args = append(args, "-go-test-fail")
+	}
+	s.callBuilder(rule, "go", "test", args...)
+
+	// The same tests with the race detector or with -short
+	s.callBuilder(builder.Rule("test-race", "init").Mkdir(filepath.Dir(out)),
+		"go", "test", append(args, "-go-test-race")...)
+	s.callBuilder(builder.Rule("test-short", "init").Mkdir(filepath.Dir(out)),
+		"go", "test", append(args, "-go-test-short")...)
+
+	// Test on every permitted platform the host can run, compiling the tests of the others.
+	// The platforms are passed on as the blocklist is not loaded when -go is run
+	crossArgs := append([]string{}, args...)
+	for _, a := range arches {
+		crossArgs = append(crossArgs, a.Platform())
+	}
+	s.callBuilder(builder.Rule("test-cross", "init").Mkdir(filepath.Dir(out)),
+		"go", "test-cross", crossArgs...)
+
+	// Benchmarks compared against the baseline
+	s.callBuilder(builder.Rule("bench", "init").Mkdir(filepath.Dir(out)),
+		"go", "bench")
+
+	// Run each fuzz target for its fuzz time
+	s.callBuilder(builder.Rule("fuzz", "init").Mkdir(filepath.Dir(out)),
+		"go", "fuzz", args...)
+}
+
+// integration adds the rule running the integration tests against the tools built for the build platform
+func (s *Build) integration(builder makefile.Builder, arches []arch.Arch, hostTarget bool) {
+	deps := []string{"init"}
+	if hostTarget && slices.ContainsFunc(arches, func(a arch.Arch) bool { return a.Target() == s.buildArch.Target() }) {
+		deps = append(deps, s.buildArch.Target()+"_tools")
+	}
+
+	var args []string
+	if *s.Go.FailTests {
+		args = append(args, "-go-test-fail")
+	}
+	s.callBuilder(builder.Rule("integration", deps...).Mkdir(*s.Encoder.Dest),
+		"go", "integration", args...)
+}
+
+// toolSrc returns the Makefile variable holding the source files a tool depends on
+func toolSrc(tool string) string {
+	return "SRC_" + tool
+}
+
+// toolSources adds a variable per tool listing the source files it depends on.
+// These are used as prerequisites so make rebuilds a tool when its source changes.
+func (s *Build) toolSources(tools []string, builder makefile.Builder) error {
+	var pkgs []string
+	for _, tool := range tools {
+		pkgs = append(pkgs, "./"+filepath.ToSlash(filepath.Join("tools", tool, "bin")))
+	}
+
+	sources, err := godeps.Sources(pkgs...)
+	if err != nil {
+		return err
+	}
+
+	if len(tools) > 0 {
+		builder.Blank().
+			Comment("Source files each tool depends on")
+	}
+
+	for _, tool := range tools {
+		files := sources[filepath.ToSlash(filepath.Join("tools", tool, "bin"))]
+		builder.SetVar(toolSrc(tool), "%s", strings.Join(files, " \\\n\t"))
+	}
+	return nil
+}
+
+// moduleHash adds the rule generating the hash of the module sources used by the build cache.
+// This runs once rather than for every tool & platform.
+func (s *Build) moduleHash(tools []string, builder makefile.Builder) {
+	if len(tools) == 0 || !buildCacheEnabled() {
+		return
+	}
+
+	var deps []string
+	for _, tool := range tools {
+		deps = append(deps, "$("+toolSrc(tool)+")")
+	}
+	builder.Rule(moduleHashFile(*s.Encoder.Dest), deps...).
+		Line("@$(BUILD) -d %s -cache-module", *s.Encoder.Dest)
 }
 
 // Build a tool in go
 func (s *Build) goBuild(arch arch.Arch, target makefile.Builder, tool string, _ *meta.Meta) {
 	dest := arch.Tool(*s.Encoder.Dest, tool)
 
-	rule := target.Rule(dest).
+	deps := []string{"$(" + toolSrc(tool) + ")"}
+	if buildCacheEnabled() {
+		deps = append(deps, moduleHashFile(*s.Encoder.Dest))
+	}
+
+	rule := target.Rule(dest, deps...).
 		Mkdir(filepath.Dir(dest))
 
 	if arch.GOARM == "" {
@@ -431,25 +627,45 @@ func (s *Build) addDocumentation(name string, docs DocumentationList, root makef
 }
 
 func (s *Build) jenkinsfile(arches []arch.Arch) error {
+	// Only needed here, so a broken jenkins.yaml does not stop any other command
+	if err := s.loadJenkinsConfig(); err != nil {
+		return err
+	}
+
+	if *s.Declarative {
+		return s.declarativeJenkinsfile(arches)
+	}
 
 	builder := jenkinsfile.New()
 
-	builder.Begin("properties([").
-		Array().
-		Begin("buildDiscarder(").
-		Begin("logRotator(").
-		Array().
-		Property("artifactDaysToKeepStr", "").
-		Property("artifactNumToKeepStr", "").
-		Property("daysToKeepStr", "").
-		Property("numToKeepStr", 10).
-		End().End().
-		Simple("disableConcurrentBuilds").
-		Simple("disableResume").
-		Begin("pipelineTriggers([").
-		Simple("cron", `"H H * * *"`)
+	c := s.jenkinsConfig
+	c.configure(builder)
+
+	properties := builder.Begin("properties([").
+		Array()
+	c.options(properties)
+	if len(c.Parameters) > 0 {
+		c.parameters(properties.Begin("parameters([").Array())
+	}
+	if c.hasTriggers() {
+		c.triggers(properties.Begin("pipelineTriggers([").Array())
+	}
 
 	node := builder.Node(*s.BuildNode)
+	if c.Timeout > 0 {
+		node = node.Timeout(c.Timeout)
+	}
+
+	// With post extensions wrap the stages so the extensions always run
+	var post jenkinsfile.Builder
+	if !s.jenkinsPost.IsEmpty() {
+		post = node
+		node = post.Block("try {", "")
+		post.Block("} catch (err) {", "").
+			Line("currentBuild.result = 'FAILURE'").
+			Line("throw err")
+		post = post.Block("} finally {", "}")
+	}
 
 	node.Stage("Checkout").
 		Line("checkout scm")
@@ -457,13 +673,22 @@ func (s *Build) jenkinsfile(arches []arch.Arch) error {
 	node.Stage("Init").
 		Sh("make clean init")
 
-	node.Stage("Test").
-		Sh("make test")
+	s.jenkinsTest(node.Stage("Test"))
+
+	stashes := &jenkinsStashes{}
+	s.jenkinsNativeTests(node, arches, stashes)
+	s.jenkinsFuzz(node)
 
 	if *s.BuildLocal {
 		// Build against the local platform only
-		node.Stage("Build").
-			Sh("make -f Makefile.gen all")
+		// Notify on completion is handled by the post extensions
+		if post != nil {
+			node.Stage("Build").
+				Sh("make -f Makefile.gen all NOTIFY=none")
+		} else {
+			node.Stage("Build").
+				Sh("make -f Makefile.gen all")
+		}
 	} else {
 		// Cross Build against the supported/requested platforms
 		// Map of stages -> arch -> steps
@@ -485,7 +710,7 @@ func (s *Build) jenkinsfile(arches []arch.Arch) error {
 						builder: stage.builder.Stage(arch.Arch()),
 					}
 				}
-				stage1.builder.Sh("make -f Makefile.gen " + arch.Target())
+				s.jenkinsBuildTarget(stage1.builder, arch, stashes)
 
 				stages[arch.GOOS] = stage
 			} else {
@@ -495,7 +720,7 @@ func (s *Build) jenkinsfile(arches []arch.Arch) error {
 				if stage == nil {
 					stage = NewOsStage(node, arch, arch.Target())
 				}
-				stage.builder.Sh("make -f Makefile.gen " + arch.Target())
+				s.jenkinsBuildTarget(stage.builder, arch, stashes)
 
 				stages[arch.Target()] = stage
 			}
@@ -507,6 +732,10 @@ func (s *Build) jenkinsfile(arches []arch.Arch) error {
 		}
 	}
 
+	s.jenkinsIntegration(node)
+
+	stashes.packageStage(node, *s.Encoder.Dest, *s.Dist, *s.ArchiveArtifacts == "")
+
 	s.jenkins.ForEach(func(e Jenkins) {
 		e.Do(builder, node)
 	})
@@ -517,6 +746,12 @@ func (s *Build) jenkinsfile(arches []arch.Arch) error {
 			ArchiveArtifacts(*s.ArchiveArtifacts)
 	}
 
+	if post != nil {
+		s.jenkinsPost.ForEach(func(e Jenkins) {
+			e.Do(builder, post)
+		})
+	}
+
 	return os.WriteFile("Jenkinsfile", []byte(builder.Build()), 0644)
 }
 
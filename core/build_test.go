@@ -0,0 +1,62 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/arch"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// newTestBuild returns a Build with its flags set to their zero values, running in an empty directory.
+// set is called to change the flags before it is used.
+func newTestBuild(t *testing.T, set func(b *Build)) *Build {
+	t.Chdir(t.TempDir())
+
+	b := &Build{Encoder: &Encoder{}, Go: &Go{}}
+	for _, v := range []any{b, b.Encoder, b.Go} {
+		initFlags(reflect.ValueOf(v).Elem())
+	}
+	*b.Encoder.Dest = "builds"
+	*b.Dist = "dist"
+	*b.BuildNode = "go"
+
+	if set != nil {
+		set(b)
+	}
+	return b
+}
+
+// initFlags sets the nil flags of a service to point to their zero value
+func initFlags(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if _, flag := v.Type().Field(i).Tag.Lookup("kernel"); flag && f.Kind() == reflect.Pointer && f.IsNil() {
+			if e := f.Type().Elem().Kind(); e != reflect.Struct {
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+		}
+	}
+}
+
+// testArches are the platforms used by the generator tests
+var testArches = []arch.Arch{
+	{GOOS: "darwin", GOARCH: "arm64"},
+	{GOOS: "linux", GOARCH: "amd64"},
+	{GOOS: "linux", GOARCH: "arm64"},
+}
+
+// writeTestFile writes a file used by a generator, e.g. jenkins.yaml
+func writeTestFile(t *testing.T, name, content string) {
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile returns the content of a generated file
+func readTestFile(t *testing.T, name string) string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,254 @@
+package core
+
+import (
+	"bytes"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/cache"
+	"github.com/peter-mount/go-build/util/godeps"
+	"github.com/peter-mount/go-build/util/makefile"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache reports on the build cache used by "-go build".
+//
+// The cache is configured with environment variables:
+// BUILD_CACHE=on enables the cache,
+// BUILD_CACHE_DIR is the cache directory, defaulting to go-build-tools within the user's cache directory,
+// BUILD_CACHE_URL is the url of an optional shared remote cache,
+// BUILD_CACHE_MODE is "read" (the default) to only read from the remote cache or "write" to also upload to it,
+// BUILD_CACHE_TIMEOUT is the timeout for remote requests, defaulting to 10s.
+type Cache struct {
+	Encoder *Encoder `kernel:"inject"`
+	Build   *Build   `kernel:"inject"`
+	Stats   *bool    `kernel:"flag,cache-stats,show and reset build cache statistics"`
+	Module  *bool    `kernel:"flag,cache-module,write the module source hash used by the build cache"`
+}
+
+// buildCacheEnv are the environment variables that affect the output of go build
+var buildCacheEnv = []string{
+	"CGO_ENABLED", "GOOS", "GOARCH", "GOARM",
+	"GO386", "GOAMD64", "GOARM64", "GOMIPS", "GOMIPS64", "GOPPC64", "GORISCV64", "GOWASM",
+	"GOEXPERIMENT", "GOFLAGS", "GOTOOLCHAIN",
+}
+
+func (s *Cache) Start() error {
+	if buildCacheEnabled() {
+		s.Build.AddCompletion(s.completion)
+	}
+
+	if *s.Module {
+		_, err := writeModuleHash(*s.Encoder.Dest)
+		return err
+	}
+
+	if *s.Stats {
+		return s.stats()
+	}
+	return nil
+}
+
+// completion shows the statistics once the all target has completed
+func (s *Cache) completion(b makefile.Builder) makefile.Builder {
+	return b.Line("@$(BUILD) -d %s -cache-stats", *s.Encoder.Dest)
+}
+
+func (s *Cache) stats() error {
+	fileName := buildCacheStats(*s.Encoder.Dest)
+	stats, err := cache.ReadStats(fileName)
+	if err == nil && stats.Total() > 0 {
+		util.Label("GO CACHE", "%s", stats)
+		err = os.Remove(fileName)
+	}
+	return err
+}
+
+// buildCacheEnabled returns true if the cache has been enabled with BUILD_CACHE=on
+func buildCacheEnabled() bool {
+	return strings.EqualFold(os.Getenv("BUILD_CACHE"), "on")
+}
+
+// newBuildCache returns the Cache to use or nil if caching is disabled
+func newBuildCache() cache.Cache {
+	if !buildCacheEnabled() {
+		return nil
+	}
+
+	dir := os.Getenv("BUILD_CACHE_DIR")
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(userDir, "go-build-tools")
+	}
+
+	local := cache.NewLocal(dir)
+
+	url := os.Getenv("BUILD_CACHE_URL")
+	if url == "" {
+		return local
+	}
+
+	timeout, err := time.ParseDuration(os.Getenv("BUILD_CACHE_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	write := strings.EqualFold(os.Getenv("BUILD_CACHE_MODE"), "write")
+
+	return cache.Tiered{local, cache.NewRemote(url, write, timeout)}
+}
+
+// buildCacheStats returns the file used to collect the statistics
+func buildCacheStats(builds string) string {
+	return filepath.Join(builds, ".cache-stats")
+}
+
+func (s *Go) recordCache(hit bool, name string) {
+	// Statistics are informational so ignore any errors
+	_ = cache.Record(buildCacheStats(*s.Encoder.Dest), hit, name)
+}
+
+// cacheKey returns the cache key for a tool built with env and ldFlags
+func (s *Go) cacheKey(tool string, env, ldFlags []string) (string, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command("go", "env", "GOVERSION")
+	cmd.Stdout = &buf
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	hash, err := readModuleHash(*s.Encoder.Dest)
+	if err != nil {
+		return "", err
+	}
+
+	return buildCacheKey(tool, strings.TrimSpace(buf.String()), hash, env, ldFlags), nil
+}
+
+// buildCacheKey returns the cache key for a tool.
+//
+// The key covers the module sources, go version, environment and the ldFlags.
+// The version stamp is not part of the key. It holds the version, user and build time,
+// so would stop different users, or developers and CI, from sharing entries.
+func buildCacheKey(tool, goVersion, moduleHash string, env, ldFlags []string) string {
+	key := cache.NewKey().
+		Add("tool", tool).
+		Add("go", goVersion)
+
+	// env has our values appended to the os environment so the last one wins
+	values := make(map[string]string)
+	for _, e := range env {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			values[k] = v
+		}
+	}
+	for _, k := range buildCacheEnv {
+		key.Add(k, values[k])
+	}
+
+	var flags []string
+	for _, f := range ldFlags {
+		if !strings.HasPrefix(f, versionLdFlag) {
+			flags = append(flags, f)
+		}
+	}
+	key.Add("ldflags", strings.Join(flags, " "))
+
+	return key.Add("module", moduleHash).String()
+}
+
+// moduleHashFile returns the file holding the hash of the module sources.
+// The Makefile generates it once, so each tool and platform does not have to list and hash the module again.
+// The first line is the hash, followed by the files it covers.
+func moduleHashFile(builds string) string {
+	return filepath.Join(builds, ".module-hash")
+}
+
+// moduleHash returns the hash of every source file in the module and the files hashed
+func moduleHash() (string, []string, error) {
+	files, err := godeps.ModuleSources()
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := cache.NewKey()
+	for _, f := range files {
+		if err := key.AddFile(f); err != nil {
+			return "", nil, err
+		}
+	}
+	return key.String(), files, nil
+}
+
+// writeModuleHash calculates the module hash and writes it to moduleHashFile
+func writeModuleHash(builds string) (string, error) {
+	hash, files, err := moduleHash()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(builds, 0755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file then rename as with make -j another build may be reading it
+	tmp, err := os.CreateTemp(builds, ".module-hash-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(strings.Join(append([]string{hash}, files...), "\n") + "\n")
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), moduleHashFile(builds))
+	}
+	return hash, err
+}
+
+// readModuleHash returns the hash written by writeModuleHash.
+// If the file does not exist or is out of date, e.g. sources changed and "-go build" was run
+// outside make, then the hash is calculated again.
+func readModuleHash(builds string) (string, error) {
+	fileName := moduleHashFile(builds)
+	info, err := os.Stat(fileName)
+	if err == nil {
+		var b []byte
+		b, err = os.ReadFile(fileName)
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if err == nil && len(lines) > 1 && moduleHashCurrent(lines[1:], info.ModTime()) {
+			return lines[0], nil
+		}
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	return writeModuleHash(builds)
+}
+
+// moduleHashCurrent returns true if none of the files, or the directories containing them,
+// have changed since t. A directory changes when files are added to or removed from it.
+func moduleHashCurrent(files []string, t time.Time) bool {
+	paths := make(map[string]bool)
+	for _, f := range files {
+		for p := filepath.FromSlash(f); !paths[p]; p = filepath.Dir(p) {
+			paths[p] = true
+		}
+	}
+
+	for p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.ModTime().Before(t) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildCacheKey(t *testing.T) {
+	env := []string{"PATH=/usr/bin", "CGO_ENABLED=0", "GOOS=linux", "GOARCH=amd64", "GOARM="}
+
+	key := func(user, version, buildTime string, env []string) string {
+		t.Setenv("USER", user)
+		t.Setenv("BUILD_VERSION", version)
+		t.Setenv("BUILD_TIME", buildTime)
+		s := &Go{}
+		return buildCacheKey("tool", "go1.22.0", "abcd", env, s.ldFlags("linux", "amd64", "", "tool"))
+	}
+
+	alice := key("alice", "v1.0.0-2-g1234567-dirty", "2024-03-01T10:00:00Z", env)
+
+	// Different users, versions & build times of the same source share an entry
+	if bob := key("bob", "v1.0.0-2-g1234567", "2024-03-02T11:30:00Z", env); bob != alice {
+		t.Errorf("key for bob %s, want %s as for alice", bob, alice)
+	}
+
+	// The platform is part of the key
+	arm := append(append([]string{}, env...), "GOARCH=arm64")
+	if got := key("alice", "v1.0.0-2-g1234567-dirty", "2024-03-01T10:00:00Z", arm); got == alice {
+		t.Errorf("key for arm64 is the same as amd64")
+	}
+
+	// As is the source
+	if got := buildCacheKey("tool", "go1.22.0", "efgh", env, nil); got == buildCacheKey("tool", "go1.22.0", "abcd", env, nil) {
+		t.Errorf("key did not change with the module hash")
+	}
+}
+
+func TestModuleHashCurrent(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	write := func(name string) {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Make everything older than the hash
+	past := time.Now().Add(-time.Hour)
+	age := func(names ...string) {
+		for _, n := range names {
+			if err := os.Chtimes(n, past, past); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	files := []string{"go.mod", "tools/a/bin/main.go", "util/util.go"}
+	for _, f := range files {
+		write(f)
+	}
+	age(append(files, ".", "tools", "tools/a", "tools/a/bin", "util")...)
+
+	now := time.Now()
+	if !moduleHashCurrent(files, now) {
+		t.Fatal("unchanged sources are not current")
+	}
+
+	// An edited file
+	write("util/util.go")
+	if moduleHashCurrent(files, now) {
+		t.Error("edited file is current")
+	}
+	age("util/util.go", "util")
+
+	// A new file in a package
+	write("util/other.go")
+	if moduleHashCurrent(files, now) {
+		t.Error("new file is current")
+	}
+	if err := os.Remove("util/other.go"); err != nil {
+		t.Fatal(err)
+	}
+	age("util")
+
+	// A new package
+	write("tools/b/bin/main.go")
+	if moduleHashCurrent(files, now) {
+		t.Error("new package is current")
+	}
+	age("tools")
+
+	// A deleted file
+	if err := os.Remove("go.mod"); err != nil {
+		t.Fatal(err)
+	}
+	if moduleHashCurrent(files, now) {
+		t.Error("deleted file is current")
+	}
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/changelog"
+	"github.com/peter-mount/go-build/util/meta"
+	"os"
+	"path/filepath"
+)
+
+type Changelog struct {
+	Build     *Build  `kernel:"inject"`
+	Changelog *bool   `kernel:"flag,changelog,generate release notes from git history"`
+	File      *string `kernel:"flag,changelog-file,prepend release notes to this file e.g. CHANGELOG.md"`
+}
+
+func (s *Changelog) Start() error {
+	if *s.Changelog {
+		return s.run()
+	}
+	return nil
+}
+
+// ReleaseNotes returns the changelog.Release for the current version
+func (s *Changelog) ReleaseNotes() (*changelog.Release, error) {
+	m, err := meta.New()
+	if err != nil {
+		return nil, err
+	}
+	return changelog.New(m.Version)
+}
+
+// ReleaseNotesFile returns the path of the release notes within dist
+func (s *Changelog) ReleaseNotesFile() string {
+	return filepath.Join(*s.Build.Dist, "release-notes.md")
+}
+
+func (s *Changelog) run() error {
+	release, err := s.ReleaseNotes()
+	if err != nil {
+		return err
+	}
+
+	notes := s.ReleaseNotesFile()
+	util.Label("CHANGELOG", "%s", notes)
+
+	err = os.MkdirAll(filepath.Dir(notes), 0755)
+	if err == nil {
+		err = os.WriteFile(notes, []byte(release.Markdown()), 0644)
+	}
+
+	if err == nil && *s.File != "" {
+		util.Label("CHANGELOG", "%s", *s.File)
+		err = release.Prepend(*s.File)
+	}
+
+	return err
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/gotest"
+	"github.com/peter-mount/go-kernel/v2/log"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Files written by the test-cross command in the build directory.
+// The results of each platform whose tests are run are written under GoTestCrossDir like those of make test.
+const (
+	GoTestCrossReport = "test-cross.json"   // Result of each platform
+	GoTestCrossFailed = "test-cross.failed" // Present when a platform failed but -go-test-fail was not set
+	GoTestCrossDir    = "test"              // Directory of the results of each platform, e.g. test/linux_386
+)
+
+// crossResult is the result of testing or compiling the tests of a platform
+type crossResult struct {
+	Platform string  `json:"platform"`
+	Mode     string  `json:"mode"` // test or compile
+	Result   string  `json:"result"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	Skipped  int     `json:"skipped"`
+	Elapsed  float64 `json:"elapsed"` // Seconds
+	Output   string  `json:"output,omitempty"`
+}
+
+// testCross runs the tests for each platform in the arguments which the host can execute,
+// and compiles them for the others so build constraints which break a platform are found early.
+// The host platform is not included as it is tested by make test.
+func (s *Go) testCross(platforms []string) error {
+	config, err := loadTestConfig()
+	if err != nil {
+		return err
+	}
+	s.testFlags(config)
+
+	// The race detector needs cgo which is disabled when cross compiling
+	config.Race = false
+
+	module, err := s.module()
+	if err != nil {
+		return err
+	}
+
+	groups, err := config.groups(module)
+	if err != nil {
+		return err
+	}
+
+	host := arch.Arch{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+	binfmt := arch.Binfmt()
+
+	dest := *s.Encoder.Dest
+	failed := filepath.Join(dest, GoTestCrossFailed)
+	if err := removeMarker(failed); err != nil {
+		return err
+	}
+
+	var results []crossResult
+	for _, p := range platforms {
+		a := parsePlatform(p)
+		if a.GOOS == host.GOOS && a.GOARCH == host.GOARCH {
+			continue
+		}
+
+		var r crossResult
+		if a.CanExecute(host, binfmt) && canRun(a) {
+			r, err = s.crossTest(config, groups, a)
+		} else {
+			r, err = s.crossCompile(groups, a)
+		}
+		if err != nil {
+			return err
+		}
+		results = append(results, r)
+	}
+
+	if err := writeReport(filepath.Join(dest, GoTestCrossReport), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if err := writeCrossTable(os.Stdout, results); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	failures := 0
+	for _, r := range results {
+		if r.Result == gotest.Fail {
+			failures++
+			fmt.Print(r.Output)
+		}
+	}
+	return s.markFailed("GO TEST", failed, failures, "platforms")
+}
+
+// crossTest runs the tests for a platform, writing its results like make test does
+func (s *Go) crossTest(config *TestConfig, groups []testGroup, a arch.Arch) (crossResult, error) {
+	r := crossResult{Platform: a.Target(), Mode: "test"}
+	dest := filepath.Join(*s.Encoder.Dest, GoTestCrossDir, a.Target())
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return r, err
+	}
+
+	util.Label("GO TEST", "%s", a.Target())
+	start := time.Now()
+
+	report := gotest.NewReport()
+	exitCode := 0
+	for _, g := range groups {
+		code, err := goTestJSONEnv(report, crossEnv(a), append(config.args(g.timeout), g.packages...))
+		if err != nil {
+			return r, err
+		}
+		if code != 0 {
+			exitCode = code
+		}
+	}
+	report.Elapsed = time.Since(start)
+	r.Elapsed = report.Elapsed.Round(time.Millisecond).Seconds()
+
+	summary := report.Summary(goTestSlowest)
+	r.Passed, r.Failed, r.Skipped = summary.Passed, summary.Failed, summary.Skipped
+
+	r.Result = gotest.Pass
+	if exitCode != 0 || report.Failed() {
+		r.Result = gotest.Fail
+		var sb strings.Builder
+		if err := report.WriteFailures(&sb); err != nil {
+			return r, err
+		}
+		if sb.Len() == 0 {
+			sb.WriteString(report.Output.String())
+		}
+		r.Output = sb.String()
+	}
+
+	return r, writeTestReports(dest, report, summary)
+}
+
+// crossCompile compiles the tests for a platform without running them
+func (s *Go) crossCompile(groups []testGroup, a arch.Arch) (crossResult, error) {
+	r := crossResult{Platform: a.Target(), Mode: "compile", Result: gotest.Pass}
+
+	// go test -c needs a directory when there is more than one package, which is thrown away
+	tmp, err := os.MkdirTemp("", "go-build-test-")
+	if err != nil {
+		return r, err
+	}
+	defer os.RemoveAll(tmp)
+
+	util.Label("GO TEST -c", "%s", a.Target())
+	start := time.Now()
+
+	for _, g := range groups {
+		cmd := exec.Command("go", append([]string{"test", "-c", "-o", tmp + "/"}, g.packages...)...)
+		cmd.Env = append(os.Environ(), crossEnv(a)...)
+		if log.IsVerbose() {
+			log.Println(cmd.String())
+		}
+
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			if _, ok := err.(*exec.ExitError); !ok {
+				return r, err
+			}
+			r.Result = gotest.Fail
+			r.Output = r.Output + compileErrors(string(out))
+		}
+	}
+
+	r.Elapsed = time.Since(start).Round(time.Millisecond).Seconds()
+	return r, nil
+}
+
+// compileErrors removes the packages without tests from the output of go test -c
+func compileErrors(out string) string {
+	var sb strings.Builder
+	for _, l := range strings.SplitAfter(out, "\n") {
+		if !strings.HasPrefix(l, "?") {
+			sb.WriteString(l)
+		}
+	}
+	return sb.String()
+}
+
+// crossEnv returns the environment to build for a platform
+func crossEnv(a arch.Arch) []string {
+	return []string{"CGO_ENABLED=0", "GOOS=" + a.GOOS, "GOARCH=" + a.GOARCH, "GOARM=" + a.GOARM}
+}
+
+// canRun checks the host can run a platform's binaries, e.g. 386 needs the kernel to support 32 bit binaries,
+// by running a program built for it
+func canRun(a arch.Arch) bool {
+	tmp, err := os.MkdirTemp("", "go-build-probe-")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(tmp)
+
+	src := filepath.Join(tmp, "main.go")
+	if err := os.WriteFile(src, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		return false
+	}
+
+	cmd := exec.Command("go", "run", src)
+	cmd.Dir = tmp
+	cmd.Env = append(os.Environ(), append(crossEnv(a), "GO111MODULE=off")...)
+	return cmd.Run() == nil
+}
+
+// parsePlatform parses a platform in the goos:goarch:goarm format of arch.Arch.Platform
+func parsePlatform(p string) arch.Arch {
+	f := append(strings.Split(p, ":"), "", "")
+	return arch.Arch{GOOS: f[0], GOARCH: f[1], GOARM: f[2]}
+}
+
+func writeCrossTable(w io.Writer, results []crossResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "Platform\tMode\tResult\tPass\tFail\tSkip\tTime")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%.3fs\n", r.Platform, r.Mode, r.Result, r.Passed, r.Failed, r.Skipped, r.Elapsed)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,247 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/gotest"
+	"github.com/peter-mount/go-kernel/v2/log"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Files written by the fuzz command in the build directory
+const (
+	GoFuzzReport = "fuzz.json"   // Result of each fuzz target
+	GoFuzzFailed = "fuzz.failed" // Present when crashers were found but -go-test-fail was not set
+	GoFuzzDir    = "fuzz"        // New crashers are copied here under the package & target
+)
+
+type FuzzConfig struct {
+	Pattern      string `yaml:"pattern"`      // Fuzz targets to run, default ^Fuzz
+	Time         string `yaml:"time"`         // -fuzztime of each target, default 30s
+	MinimizeTime string `yaml:"minimizetime"` // -fuzzminimizetime of each target
+}
+
+// fuzzResult is the result of running a fuzz target
+type fuzzResult struct {
+	Package  string   `json:"package"`
+	Target   string   `json:"target"`
+	Result   string   `json:"result"`
+	Elapsed  float64  `json:"elapsed"`            // Seconds
+	Crashers []string `json:"crashers,omitempty"` // New inputs in testdata/fuzz which failed
+	Output   string   `json:"output,omitempty"`   // Output of a failed target
+}
+
+// fuzz runs each fuzz target for the fuzz time, collecting any new crashers
+func (s *Go) fuzz() error {
+	config, err := loadTestConfig()
+	if err != nil {
+		return err
+	}
+	s.testFlags(config)
+
+	c := config.Fuzz
+	if *s.FuzzTime != "" {
+		c.Time = *s.FuzzTime
+	}
+
+	module, err := s.module()
+	if err != nil {
+		return err
+	}
+
+	groups, err := config.groups(module)
+	if err != nil {
+		return err
+	}
+	var packages []string
+	for _, g := range groups {
+		packages = append(packages, g.packages...)
+	}
+
+	targets, err := fuzzTargets(c.Pattern, packages)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := packageDirs(packages)
+	if err != nil {
+		return err
+	}
+
+	dest := *s.Encoder.Dest
+	failed := filepath.Join(dest, GoFuzzFailed)
+	if err := removeMarker(failed); err != nil {
+		return err
+	}
+
+	var results []fuzzResult
+	for _, pkg := range sortedKeys(targets) {
+		for _, target := range targets[pkg] {
+			r, err := s.fuzzTarget(config, c, module, pkg, target, dirs[pkg])
+			if err != nil {
+				return err
+			}
+			results = append(results, r)
+		}
+	}
+
+	if err := writeReport(filepath.Join(dest, GoFuzzReport), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if err := writeFuzzTable(os.Stdout, results); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	crashed := 0
+	for _, r := range results {
+		if r.Result == gotest.Fail {
+			crashed++
+			fmt.Print(r.Output)
+		}
+	}
+	return s.markFailed("GO FUZZ", failed, crashed, "fuzz targets")
+}
+
+// fuzzTarget runs a fuzz target, copying any new crashers into the build directory
+func (s *Go) fuzzTarget(config *TestConfig, c FuzzConfig, module, pkg, target, dir string) (fuzzResult, error) {
+	r := fuzzResult{Package: pkg, Target: target, Result: gotest.Pass}
+
+	// Report the corpus relative to the project
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, dir); err == nil {
+			dir = rel
+		}
+	}
+	corpus := filepath.Join(dir, "testdata", "fuzz", target)
+
+	before, err := listFiles(corpus)
+	if err != nil {
+		return r, err
+	}
+
+	args := []string{"test", "-run=^$", "-fuzz=^" + target + "$", "-fuzztime=" + c.Time}
+	if c.MinimizeTime != "" {
+		args = append(args, "-fuzzminimizetime="+c.MinimizeTime)
+	}
+	// go test has no timeout when fuzzing unless one is given
+	args = append(args, config.args("")...)
+
+	var buf bytes.Buffer
+	cmd := exec.Command("go", append(args, pkg)...)
+	cmd.Stdout = &buf
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = &buf
+
+	if log.IsVerbose() {
+		log.Println(cmd.String())
+	}
+
+	util.Label("GO FUZZ", "%s %s", pkg, target)
+	start := time.Now()
+	err = cmd.Run()
+	r.Elapsed = time.Since(start).Round(time.Millisecond).Seconds()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return r, err
+		}
+		r.Result = gotest.Fail
+		r.Output = buf.String()
+	}
+
+	after, err := listFiles(corpus)
+	if err != nil {
+		return r, err
+	}
+
+	dest := filepath.Join(*s.Encoder.Dest, GoFuzzDir, strings.TrimPrefix(pkg, module+"/"), target)
+	for _, f := range sortedKeys(after) {
+		if !before[f] {
+			r.Crashers = append(r.Crashers, filepath.Join(corpus, f))
+			if err := copyCrasher(filepath.Join(corpus, f), filepath.Join(dest, f)); err != nil {
+				return r, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// fuzzTargets returns the fuzz targets in each package
+func fuzzTargets(pattern string, packages []string) (map[string][]string, error) {
+	out, err := exec.Command("go", append([]string{"test", "-list=" + pattern}, packages...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return gotest.ParseList(bytes.NewReader(out))
+}
+
+// packageDirs returns the directory of each package
+func packageDirs(packages []string) (map[string]string, error) {
+	out, err := exec.Command("go", append([]string{"list", "-f", "{{.ImportPath}}={{.Dir}}"}, packages...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]string)
+	for _, l := range strings.Split(string(out), "\n") {
+		if pkg, dir, found := strings.Cut(l, "="); found {
+			dirs[pkg] = dir
+		}
+	}
+	return dirs, nil
+}
+
+// listFiles returns the names of the files in a directory, which may not exist
+func listFiles(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	for _, e := range entries {
+		if !e.IsDir() {
+			files[e.Name()] = true
+		}
+	}
+	return files, nil
+}
+
+// copyCrasher copies a crasher creating the directory it is copied to
+func copyCrasher(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return util.CopyFile(src, dst, info)
+}
+
+func writeFuzzTable(w io.Writer, results []fuzzResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "Package\tTarget\tResult\tTime\tCrashers")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%.3fs\t%d\n", r.Package, r.Target, r.Result, r.Elapsed, len(r.Crashers))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/meta"
+	"github.com/peter-mount/go-build/util/workflow"
+	"sort"
+)
+
+// GitHubWorkflowFile is where the GitHub Actions workflow is written
+const GitHubWorkflowFile = ".github/workflows/build.yml"
+
+type GitHubAction func(w *workflow.Workflow)
+
+func (a GitHubAction) Do(w *workflow.Workflow) {
+	if a != nil {
+		a(w)
+	}
+}
+
+type GitHubActionList []gitHubActionEntry
+
+type gitHubActionEntry struct {
+	seq   int
+	entry GitHubAction
+}
+
+func (l *GitHubActionList) Add(seq int, entry GitHubAction) {
+	*l = append(*l, gitHubActionEntry{
+		seq:   seq,
+		entry: entry,
+	})
+}
+
+func (l *GitHubActionList) ForEach(f func(GitHubAction)) {
+	sort.SliceStable(*l, func(i, j int) bool {
+		return (*l)[i].seq < (*l)[j].seq
+	})
+
+	for _, e := range *l {
+		f(e.entry)
+	}
+}
+
+// GitHubAction adds an extension to the GitHub Actions workflow.
+// Extensions are run once the test & build jobs have been added.
+func (s *Build) GitHubAction(seq int, ext GitHubAction) {
+	s.gitHubActions.Add(seq, ext)
+}
+
+// gitHubWorkflow writes the GitHub Actions workflow
+func (s *Build) gitHubWorkflow(arches []arch.Arch) error {
+	w := workflow.New("Build", meta.DefaultBranch())
+
+	w.AddJob("test", workflow.NewJob("Test", "ubuntu-latest").
+		Checkout().
+		Run("Init", "make clean init").
+		Run("Test", "make test"))
+
+	build := workflow.NewJob("Build", "ubuntu-latest")
+	build.Needs = []string{"test"}
+
+	if *s.BuildLocal {
+		// Build against the local platform only
+		build.Checkout().
+			Run("Init", "make clean init").
+			Run("Build", "make -f Makefile.gen all")
+	} else {
+		// Cross build with a job per OS when parallel, otherwise a job per target run in sequence
+		targets := make(map[string]bool)
+		for _, a := range arches {
+			if *s.Parallelize {
+				targets[a.GOOS] = true
+			} else {
+				targets[a.Target()] = true
+			}
+		}
+
+		var matrix []string
+		for t := range targets {
+			matrix = append(matrix, t)
+		}
+		sort.Strings(matrix)
+
+		failFast := false
+		build.Name = "Build ${{ matrix.target }}"
+		build.Strategy = &workflow.Strategy{
+			FailFast: &failFast,
+			Matrix:   map[string][]string{"target": matrix},
+		}
+		if !*s.Parallelize {
+			build.Strategy.MaxParallel = 1
+		}
+
+		build.Checkout().
+			Run("Init", "make clean init").
+			Run("Build", "make -f Makefile.gen ${{ matrix.target }}")
+	}
+
+	build.Uses("Upload artifacts", "actions/upload-artifact@v4", map[string]string{
+		"name":              "dist-${{ matrix.target || 'local' }}",
+		"path":              "dist/",
+		"if-no-files-found": "ignore",
+	})
+
+	w.AddJob("build", build)
+
+	s.gitHubActions.ForEach(func(e GitHubAction) {
+		e.Do(w)
+	})
+
+	out, err := w.Build()
+	if err != nil {
+		return err
+	}
+	return writeFile(GitHubWorkflowFile, out)
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/workflow"
+	"slices"
+	"testing"
+)
+
+func TestGitHubWorkflow(t *testing.T) {
+	b := newTestBuild(t, nil)
+	if err := b.gitHubWorkflow(testArches); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Generated workflow
+name: Build
+"on":
+  push:
+    branches:
+    - main
+    tags:
+    - v*
+  pull_request: {}
+  workflow_dispatch: {}
+jobs:
+  test:
+    name: Test
+    runs-on: ubuntu-latest
+    steps:
+    - name: Checkout
+      uses: actions/checkout@v4
+      with:
+        fetch-depth: "0"
+    - name: Setup go
+      uses: actions/setup-go@v5
+      with:
+        go-version-file: go.mod
+    - name: Init
+      run: make clean init
+    - name: Test
+      run: make test
+  build:
+    name: Build ${{ matrix.target }}
+    runs-on: ubuntu-latest
+    needs:
+    - test
+    strategy:
+      fail-fast: false
+      max-parallel: 1
+      matrix:
+        target:
+        - darwin_arm64
+        - linux_amd64
+        - linux_arm64
+    steps:
+    - name: Checkout
+      uses: actions/checkout@v4
+      with:
+        fetch-depth: "0"
+    - name: Setup go
+      uses: actions/setup-go@v5
+      with:
+        go-version-file: go.mod
+    - name: Init
+      run: make clean init
+    - name: Build
+      run: make -f Makefile.gen ${{ matrix.target }}
+    - name: Upload artifacts
+      uses: actions/upload-artifact@v4
+      with:
+        if-no-files-found: ignore
+        name: dist-${{ matrix.target || 'local' }}
+        path: dist/
+`
+	if got := readTestFile(t, GitHubWorkflowFile); got != want {
+		t.Errorf("gitHubWorkflow() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGitHubWorkflow_Build(t *testing.T) {
+	tests := []struct {
+		name        string
+		local       bool
+		parallel    bool
+		matrix      []string // nil when there is no matrix
+		maxParallel int
+		run         string
+	}{
+		{name: "sequential", matrix: []string{"darwin_arm64", "linux_amd64", "linux_arm64"}, maxParallel: 1, run: "make -f Makefile.gen ${{ matrix.target }}"},
+		{name: "parallel", parallel: true, matrix: []string{"darwin", "linux"}, run: "make -f Makefile.gen ${{ matrix.target }}"},
+		{name: "local", local: true, run: "make -f Makefile.gen all"},
+		{name: "local parallel", local: true, parallel: true, run: "make -f Makefile.gen all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBuild(t, func(b *Build) {
+				*b.BuildLocal = tt.local
+				*b.Parallelize = tt.parallel
+			})
+
+			// Extensions see the workflow once the jobs have been added
+			var w *workflow.Workflow
+			b.GitHubAction(0, func(wf *workflow.Workflow) {
+				w = wf
+			})
+			if err := b.gitHubWorkflow(testArches); err != nil {
+				t.Fatal(err)
+			}
+			if w == nil {
+				t.Fatal("extension not called")
+			}
+
+			if ids := w.JobIds(); !slices.Equal(ids, []string{"test", "build"}) {
+				t.Errorf("JobIds() = %v", ids)
+			}
+
+			build := w.Job("build")
+			if tt.matrix == nil {
+				if build.Strategy != nil {
+					t.Errorf("Strategy = %v, want nil", build.Strategy)
+				}
+			} else {
+				if build.Strategy == nil {
+					t.Fatal("Strategy = nil")
+				}
+				if got := build.Strategy.Matrix["target"]; !slices.Equal(got, tt.matrix) {
+					t.Errorf("matrix = %v, want %v", got, tt.matrix)
+				}
+				if build.Strategy.MaxParallel != tt.maxParallel {
+					t.Errorf("MaxParallel = %d, want %d", build.Strategy.MaxParallel, tt.maxParallel)
+				}
+			}
+
+			var runs []string
+			for _, s := range build.Steps {
+				runs = append(runs, s.Run)
+			}
+			if !slices.Contains(runs, tt.run) {
+				t.Errorf("steps run %q, want %q", runs, tt.run)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/gitlab"
+	"github.com/peter-mount/go-build/util/meta"
+	"path/filepath"
+	"sort"
+)
+
+// GitLabFile is where the GitLab CI pipeline is written
+const GitLabFile = ".gitlab-ci.yml"
+
+type GitLab func(p *gitlab.Pipeline)
+
+func (a GitLab) Do(p *gitlab.Pipeline) {
+	if a != nil {
+		a(p)
+	}
+}
+
+type GitLabList []gitLabEntry
+
+type gitLabEntry struct {
+	seq   int
+	entry GitLab
+}
+
+func (l *GitLabList) Add(seq int, entry GitLab) {
+	*l = append(*l, gitLabEntry{
+		seq:   seq,
+		entry: entry,
+	})
+}
+
+func (l *GitLabList) ForEach(f func(GitLab)) {
+	sort.SliceStable(*l, func(i, j int) bool {
+		return (*l)[i].seq < (*l)[j].seq
+	})
+
+	for _, e := range *l {
+		f(e.entry)
+	}
+}
+
+// GitLab adds an extension to the GitLab CI pipeline.
+// Extensions are run once the package stage has been added.
+func (s *Build) GitLab(seq int, ext GitLab) {
+	s.gitLab.Add(seq, ext)
+}
+
+// gitLabPipeline writes the GitLab CI pipeline
+func (s *Build) gitLabPipeline(arches []arch.Arch) error {
+	p := gitlab.New("init", "test", "build", "dist", "package")
+
+	image := "golang"
+	if v := meta.GoVersion(); v != "" {
+		image = image + ":" + v
+	}
+	p.Default = &gitlab.Default{
+		Image: image,
+		Cache: &gitlab.Cache{Key: "go-mod", Paths: []string{".go/pkg/mod/"}},
+	}
+	p.Variables = map[string]string{
+		"GOPATH":    "$CI_PROJECT_DIR/.go",
+		"GIT_DEPTH": "0", // full history so the version can be taken from the tags
+	}
+
+	builds := *s.Encoder.Dest
+	dist := *s.Dist + "/"
+
+	initJob := gitlab.NewJob("init", "make clean init")
+	initJob.Artifacts = &gitlab.Artifacts{Paths: []string{"build", "Makefile.gen"}, ExpireIn: "1 day"}
+	p.AddJob("init", initJob)
+
+	test := gitlab.NewJob("test", "make -f Makefile.gen test").Need("init")
+	test.Artifacts = &gitlab.Artifacts{
+		When:     "always",
+		Paths:    []string{filepath.Join(builds, GoTestOutput), filepath.Join(builds, GoCoverageHTML)},
+		ExpireIn: "1 week",
+		Reports: &gitlab.Reports{
+			JUnit: []string{filepath.Join(builds, GoTestJUnit)},
+			CoverageReport: &gitlab.CoverageReport{
+				CoverageFormat: "cobertura",
+				Path:           filepath.Join(builds, GoCobertura),
+			},
+		},
+	}
+	p.AddJob("test", test)
+
+	buildArtifacts := &gitlab.Artifacts{Paths: []string{builds + "/"}, ExpireIn: "1 day"}
+	distArtifacts := &gitlab.Artifacts{Paths: []string{dist}, ExpireIn: "1 week"}
+
+	switch {
+	case *s.BuildLocal:
+		// Build against the local platform only
+		build := gitlab.NewJob("build", "make -f Makefile.gen all").Need("init")
+		build.Artifacts = distArtifacts
+		p.AddJob("build", build)
+
+	case *s.Parallelize:
+		// A single job for each stage with an instance per target
+		var targets []string
+		for _, a := range arches {
+			targets = append(targets, a.Target())
+		}
+		matrix := &gitlab.Parallel{Matrix: []map[string][]string{{"TARGET": targets}}}
+
+		build := gitlab.NewJob("build", "make -f Makefile.gen ${TARGET}_tools ${TARGET}_ext").Need("init")
+		build.Parallel = matrix
+		build.Artifacts = buildArtifacts
+		p.AddJob("build", build)
+
+		d := gitlab.NewJob("dist", "make -f Makefile.gen ${TARGET}_dist").Need("init", "build")
+		d.Parallel = matrix
+		d.Artifacts = distArtifacts
+		p.AddJob("dist", d)
+
+	default:
+		// A job per target
+		for _, a := range arches {
+			t := a.Target()
+
+			build := gitlab.NewJob("build", "make -f Makefile.gen "+t+"_tools "+t+"_ext").Need("init")
+			build.Artifacts = buildArtifacts
+			p.AddJob("build "+t, build)
+
+			d := gitlab.NewJob("dist", "make -f Makefile.gen "+t+"_dist").Need("init", "build "+t)
+			d.Artifacts = distArtifacts
+			p.AddJob("dist "+t, d)
+		}
+	}
+
+	// Gather everything in dist together with their checksums.
+	// GitLab limits needs to 50 jobs, above that rely on the stages instead
+	distStage := "dist"
+	if *s.BuildLocal {
+		distStage = "build"
+	}
+
+	pkg := gitlab.NewJob("package", "cd "+*s.Dist+" && sha256sum * > SHA256SUMS")
+	if needs := p.JobNames(distStage); len(needs) <= 50 {
+		pkg.Need(needs...)
+	}
+	pkg.Artifacts = &gitlab.Artifacts{Paths: []string{dist}, ExpireIn: "1 month"}
+	p.AddJob("package", pkg)
+
+	s.gitLab.ForEach(func(e GitLab) {
+		e.Do(p)
+	})
+
+	out, err := p.Build()
+	if err != nil {
+		return err
+	}
+	return writeFile(GitLabFile, out)
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"fmt"
+	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/gitlab"
+	"strings"
+	"testing"
+)
+
+func TestGitLabPipeline(t *testing.T) {
+	b := newTestBuild(t, nil)
+	if err := b.gitLabPipeline(testArches); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Generated GitLab CI pipeline
+stages:
+- init
+- test
+- build
+- dist
+- package
+default:
+  image: golang
+  cache:
+    key: go-mod
+    paths:
+    - .go/pkg/mod/
+variables:
+  GIT_DEPTH: "0"
+  GOPATH: $CI_PROJECT_DIR/.go
+init:
+  stage: init
+  script:
+  - make clean init
+  artifacts:
+    paths:
+    - build
+    - Makefile.gen
+    expire_in: 1 day
+test:
+  stage: test
+  needs:
+  - init
+  script:
+  - make -f Makefile.gen test
+  artifacts:
+    when: always
+    paths:
+    - builds/go-test.txt
+    - builds/coverage.html
+    expire_in: 1 week
+    reports:
+      junit:
+      - builds/go-test.xml
+      coverage_report:
+        coverage_format: cobertura
+        path: builds/coverage.xml
+` + gitLabPlatformJobs(testArches) + `package:
+  stage: package
+  needs:
+  - dist darwin_arm64
+  - dist linux_amd64
+  - dist linux_arm64
+  script:
+  - cd dist && sha256sum * > SHA256SUMS
+  artifacts:
+    paths:
+    - dist/
+    expire_in: 1 month
+`
+	if got := readTestFile(t, GitLabFile); got != want {
+		t.Errorf("gitLabPipeline() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestGitLabPipeline_Needs checks the package job only needs the dist jobs while there are at most 50 of them
+func TestGitLabPipeline_Needs(t *testing.T) {
+	for _, n := range []int{26, 50, 51} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			var arches []arch.Arch
+			for i := 0; i < n; i++ {
+				arches = append(arches, arch.Arch{GOOS: "linux", GOARCH: fmt.Sprintf("arch%02d", i)})
+			}
+
+			b := newTestBuild(t, nil)
+			var p *gitlab.Pipeline
+			b.GitLab(0, func(pl *gitlab.Pipeline) {
+				p = pl
+			})
+			if err := b.gitLabPipeline(arches); err != nil {
+				t.Fatal(err)
+			}
+			if p == nil {
+				t.Fatal("extension not called")
+			}
+
+			// init, test, a build & dist job per platform then package
+			if got, want := len(p.Jobs), 2*n+3; got != want {
+				t.Errorf("%d jobs, want %d", got, want)
+			}
+
+			got := readTestFile(t, GitLabFile)
+			if jobs := gitLabPlatformJobs(arches); !strings.Contains(got, jobs) {
+				t.Errorf("platform jobs missing, want\n%s", jobs)
+			}
+
+			pkg := "package:\n  stage: package\n"
+			if n <= 50 {
+				pkg += "  needs:\n"
+				for _, a := range arches {
+					pkg += "  - dist " + a.Target() + "\n"
+				}
+			}
+			pkg += "  script:\n  - cd dist && sha256sum * > SHA256SUMS\n"
+			if !strings.Contains(got, pkg) {
+				t.Errorf("gitLabPipeline() =\n%s\nwant package job\n%s", got, pkg)
+			}
+		})
+	}
+}
+
+// gitLabPlatformJobs returns the build & dist jobs expected for each platform
+func gitLabPlatformJobs(arches []arch.Arch) string {
+	var s strings.Builder
+	for _, a := range arches {
+		t := a.Target()
+		fmt.Fprintf(&s, `build %[1]s:
+  stage: build
+  needs:
+  - init
+  script:
+  - make -f Makefile.gen %[1]s_tools %[1]s_ext
+  artifacts:
+    paths:
+    - builds/
+    expire_in: 1 day
+dist %[1]s:
+  stage: dist
+  needs:
+  - init
+  - build %[1]s
+  script:
+  - make -f Makefile.gen %[1]s_dist
+  artifacts:
+    paths:
+    - dist/
+    expire_in: 1 week
+`, t)
+	}
+	return s.String()
+}
@@ -1,7 +1,6 @@
 package core
 
 import (
-	"bytes"
 	"errors"
 	"flag"
 	"fmt"
@@ -16,9 +15,21 @@ import (
 )
 
 type Go struct {
-	Encoder   *Encoder `kernel:"inject"`
-	Go        *string  `kernel:"flag,go,call GO"`
-	FailTests *bool    `kernel:"flag,go-test-fail,on test failure abort the build"`
+	Encoder    *Encoder `kernel:"inject"`
+	Go         *string  `kernel:"flag,go,call GO"`
+	FailTests  *bool    `kernel:"flag,go-test-fail,on test failure abort the build"`
+	Race       *bool    `kernel:"flag,go-test-race,run the tests with the race detector"`
+	Short      *bool    `kernel:"flag,go-test-short,run the tests with -short"`
+	Shuffle    *string  `kernel:"flag,go-test-shuffle,shuffle the tests: on or off or a seed"`
+	Count      *int     `kernel:"flag,go-test-count,run each test n times"`
+	Retries    *int     `kernel:"flag,go-test-retries,run failed tests again up to n times to detect flaky tests"`
+	Include    *string  `kernel:"flag,go-test-include,packages to test separated by commas"`
+	Exclude    *string  `kernel:"flag,go-test-exclude,packages not to test separated by commas"`
+	Timeout    *string  `kernel:"flag,go-test-timeout,timeout of each package e.g. 10m"`
+	BenchTime  *string  `kernel:"flag,go-bench-time,benchtime of each benchmark e.g. 1s or 100x"`
+	BenchCount *int     `kernel:"flag,go-bench-count,run each benchmark n times"`
+	BenchSave  *bool    `kernel:"flag,go-bench-save,save the results as the baseline"`
+	FuzzTime   *string  `kernel:"flag,go-fuzz-time,fuzztime of each fuzz target e.g. 30s or 1000x"`
 }
 
 func (s *Go) Start() error {
@@ -40,6 +51,18 @@ func (s *Go) run() error {
 	case "test":
 		return s.test()
 
+	case "test-cross":
+		return s.testCross(flag.Args())
+
+	case "integration":
+		return s.integration()
+
+	case "bench":
+		return s.bench()
+
+	case "fuzz":
+		return s.fuzz()
+
 	default:
 		return fmt.Errorf("unknown GO command %q", *s.Go)
 	}
@@ -73,8 +96,6 @@ func (s *Go) buildTool(goos, goarch, goarm, tool string) error {
 		dst = dst + ".exe"
 	}
 
-	util.Label("GO BUILD", dst)
-
 	// The os environment then add our vars
 	env := append([]string{}, os.Environ()...)
 	env = append(env, "CGO_ENABLED=0",
@@ -86,7 +107,60 @@ func (s *Go) buildTool(goos, goarch, goarm, tool string) error {
 	var args []string
 	args = append(args, "build")
 
-	// ldFlags
+	ldFlags := s.ldFlags(goos, goarch, goarm, tool)
+	args = append(args, "-ldflags="+strings.Join(ldFlags, " "))
+
+	args = append(args, "-o", dst, src)
+
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+
+	if log.IsVerbose() {
+		log.Println(cmd.String())
+	}
+
+	// Restore from the build cache if we can
+	var (
+		key string
+		err error
+	)
+	buildCache := newBuildCache()
+	if buildCache != nil {
+		key, err = s.cacheKey(tool, env, ldFlags)
+		if err != nil {
+			return err
+		}
+
+		hit, err := buildCache.Get(key, dst)
+		if err != nil {
+			return err
+		}
+		if hit {
+			util.Label("GO CACHED", "%s", dst)
+			s.recordCache(true, dst)
+			return nil
+		}
+	}
+
+	util.Label("GO BUILD", "%s", dst)
+	err = cmd.Run()
+
+	if err == nil && buildCache != nil {
+		s.recordCache(false, dst)
+		err = buildCache.Put(key, dst)
+	}
+
+	return err
+}
+
+// versionLdFlag is the start of the linker flag which sets the version
+const versionLdFlag = `-X 'github.com/peter-mount/go-build/version.Version=`
+
+// ldFlags returns the flags to pass to the linker
+func (s *Go) ldFlags(goos, goarch, goarm, tool string) []string {
 	var ldFlags []string
 
 	// Set Version if we have BUILD_VERSION and BUILD_TIME in the environment
@@ -106,7 +180,7 @@ func (s *Go) buildTool(goos, goarch, goarm, tool string) error {
 		// Version based on the tool definitions
 		ldFlags = append(ldFlags,
 			fmt.Sprintf(
-				`-X 'github.com/peter-mount/go-build/version.Version=%s (%s %s %s %s %s)'`,
+				versionLdFlag+`%s (%s %s %s %s %s)'`,
 				tool,
 				buildVersion,
 				goos, goarch+goarm,
@@ -131,55 +205,21 @@ func (s *Go) buildTool(goos, goarch, goarm, tool string) error {
 		"-w", // Disable DWARF generation
 	)
 
-	args = append(args, "-ldflags="+strings.Join(ldFlags, " "))
-
-	args = append(args, "-o", dst, src)
-
-	cmd := exec.Command("go", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.Env = env
-
-	if log.IsVerbose() {
-		log.Println(cmd.String())
-	}
-
-	return cmd.Run()
+	return ldFlags
 }
 
-func (s *Go) test() error {
-	var buf bytes.Buffer
+// writeReport creates a file and writes a report to it
+func writeReport(name string, f func(io.Writer) error) error {
+	util.Label("GO REPORT", "%s", name)
 
-	testOut := filepath.Join(*s.Encoder.Dest, "go-test.txt")
-	f, err := os.Create(testOut)
+	w, err := os.Create(name)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	w := io.MultiWriter(&buf, f)
-	cmd := exec.Command("go", "test", "./...")
-	cmd.Stdout = w
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = w
-
-	if log.IsVerbose() {
-		log.Println(cmd.String())
-	}
-
-	util.Label("GO TEST", testOut)
-
-	err = cmd.Run()
-	if exit, ok := err.(*exec.ExitError); ok {
-		fmt.Printf("Tests returned %d\n",
-			exit.ExitCode())
-		fmt.Println(buf.String())
-
-		// Default don't fail the build on test failures
-		if !*s.FailTests {
-			return nil
-		}
+	err = f(w)
+	if err1 := w.Close(); err == nil {
+		err = err1
 	}
 	return err
 }
@@ -13,5 +13,10 @@ func init() {
 		&Tar{},
 		&Zip{},
 		&Apt{},
+		&Changelog{},
+		&Release{},
+		&Publish{},
+		&Notify{},
+		&Cache{},
 	)
 }
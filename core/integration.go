@@ -0,0 +1,147 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/arch"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Files written by the integration command in the build directory
+const (
+	GoIntegrationDir    = "integration"        // The test results in the same format as make test
+	GoIntegrationFailed = "integration.failed" // Present when tests failed but -go-test-fail was not set
+)
+
+// IntegrationBinDir is the environment variable holding the host's bin directory when running the integration tests.
+// It is only set for the tests, the application package does not read it.
+const IntegrationBinDir = "BUILD_BIN_DIR"
+
+type IntegrationConfig struct {
+	Tags    string `yaml:"tags"`    // Build tags of the integration tests, default integration
+	Timeout string `yaml:"timeout"` // Timeout of each package, default the timeout of the tests
+}
+
+// integration runs the tests in packages with test files only built with the integration tags.
+// The tests run against the tools built for the host, which are found by IntegrationBinDir.
+// Run from there the tools use the flat application layout of the host's directory under builds.
+func (s *Go) integration() error {
+	config, err := loadTestConfig()
+	if err != nil {
+		return err
+	}
+	s.testFlags(config)
+
+	c := config.Integration
+	config.tags = c.Tags
+	if c.Timeout != "" && *s.Timeout == "" {
+		config.Timeout = c.Timeout
+	}
+
+	module, err := s.module()
+	if err != nil {
+		return err
+	}
+
+	groups, err := config.groups(module)
+	if err != nil {
+		return err
+	}
+
+	// Only the packages with integration tests
+	var tagged []testGroup
+	for _, g := range groups {
+		packages, err := taggedPackages(c.Tags, g.packages)
+		if err != nil {
+			return err
+		}
+		if len(packages) > 0 {
+			tagged = append(tagged, testGroup{timeout: g.timeout, packages: packages})
+		}
+	}
+
+	dest := *s.Encoder.Dest
+	out := filepath.Join(dest, GoIntegrationDir)
+	failed := filepath.Join(dest, GoIntegrationFailed)
+	if err := removeMarker(failed); err != nil {
+		return err
+	}
+
+	if len(tagged) == 0 {
+		util.Label("GO TEST", "no packages with %s tests", c.Tags)
+		return nil
+	}
+
+	if err := integrationEnv(dest); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+
+	util.Label("GO TEST", "%s", filepath.Join(out, GoTestOutput))
+
+	report, exitCode, err := config.runTests(tagged, nil)
+	if err != nil {
+		return err
+	}
+	return s.reportTests(report, exitCode, out, failed)
+}
+
+// integrationEnv points the tests at the host's bin directory under builds
+func integrationEnv(dest string) error {
+	host := arch.Arch{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+	bin, err := filepath.Abs(filepath.Join(host.BaseDir(dest), "bin"))
+	if err != nil {
+		return err
+	}
+
+	if err := os.Setenv(IntegrationBinDir, bin); err != nil {
+		return err
+	}
+
+	util.Label("GO TEST", "%s=%s", IntegrationBinDir, bin)
+	return nil
+}
+
+// taggedPackages returns the packages which have test files only built with the tags
+func taggedPackages(tags string, packages []string) ([]string, error) {
+	without, err := testFiles("", packages)
+	if err != nil {
+		return nil, err
+	}
+
+	with, err := testFiles(tags, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagged []string
+	for _, pkg := range sortedKeys(with) {
+		if !slices.Equal(with[pkg], without[pkg]) {
+			tagged = append(tagged, pkg)
+		}
+	}
+	return tagged, nil
+}
+
+// testFiles returns the test files of each package when built with the tags
+func testFiles(tags string, packages []string) (map[string][]string, error) {
+	args := []string{"list", "-tags=" + tags, "-f", "{{.ImportPath}}{{range .TestGoFiles}} {{.}}{{end}}{{range .XTestGoFiles}} {{.}}{{end}}"}
+	out, err := exec.Command("go", append(args, packages...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]string)
+	for _, l := range strings.Split(string(out), "\n") {
+		if f := strings.Fields(l); len(f) > 0 {
+			files[f[0]] = f[1:]
+		}
+	}
+	return files, nil
+}
@@ -1,10 +1,157 @@
 package core
 
 import (
+	"fmt"
+	"github.com/peter-mount/go-build/util/arch"
 	"github.com/peter-mount/go-build/util/jenkinsfile"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 )
 
+// JenkinsConfig is the content of jenkins.yaml, configuring the generated Jenkinsfile
+type JenkinsConfig struct {
+	LogRotator       JenkinsLogRotator       `yaml:"logRotator"`
+	Triggers         JenkinsTriggers         `yaml:"triggers"`
+	ConcurrentBuilds bool                    `yaml:"concurrentBuilds"` // Allow concurrent builds
+	Resume           bool                    `yaml:"resume"`           // Allow the build to resume after a restart
+	Timeout          int                     `yaml:"timeout"`          // Timeout of the build in minutes, 0 for none
+	Parameters       []JenkinsParameter      `yaml:"parameters"`
+	Stages           map[string]JenkinsStage `yaml:"stages"`      // Options for each stage by name
+	Agents           map[string]string       `yaml:"agents"`      // Agent labels by target, GOOS or GOARCH
+	NativeTests      []string                `yaml:"nativeTests"` // Targets to also run the tests on natively
+	Fuzz             bool                    `yaml:"fuzz"`        // Run the fuzz targets when built by the cron trigger
+	Integration      bool                    `yaml:"integration"` // Run the integration tests once the platforms are built
+}
+
+type JenkinsLogRotator struct {
+	NumToKeep          string `yaml:"numToKeep"`
+	DaysToKeep         string `yaml:"daysToKeep"`
+	ArtifactNumToKeep  string `yaml:"artifactNumToKeep"`
+	ArtifactDaysToKeep string `yaml:"artifactDaysToKeep"`
+}
+
+type JenkinsTriggers struct {
+	Cron              string `yaml:"cron"`
+	PollSCM           string `yaml:"pollSCM"`
+	Upstream          string `yaml:"upstream"`          // Comma separated list of upstream jobs
+	UpstreamThreshold string `yaml:"upstreamThreshold"` // Result of the upstream job, default SUCCESS
+}
+
+type JenkinsParameter struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"` // string, text, boolean or choice, default string
+	Default     string   `yaml:"default"`
+	Description string   `yaml:"description"`
+	Choices     []string `yaml:"choices"`
+}
+
+type JenkinsStage struct {
+	Timeout int `yaml:"timeout"` // Timeout in minutes, 0 for none
+	Retry   int `yaml:"retry"`   // Number of times to retry, 0 for none
+}
+
+// loadJenkinsConfig loads jenkins.yaml, keeping the defaults if it does not exist
+func (s *Build) loadJenkinsConfig() error {
+	s.jenkinsConfig = &JenkinsConfig{
+		LogRotator: JenkinsLogRotator{NumToKeep: "10"},
+		Triggers:   JenkinsTriggers{Cron: "H H * * *"},
+	}
+
+	b, err := os.ReadFile("jenkins.yaml")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	return yaml.Unmarshal(b, s.jenkinsConfig)
+}
+
+// configure applies the per stage options to the builder
+func (c *JenkinsConfig) configure(builder jenkinsfile.Builder) {
+	for name, stage := range c.Stages {
+		builder.StageOptions(name, stage.Timeout, stage.Retry)
+	}
+}
+
+// agent returns the label of the agent for a target, "" to use the pipeline's node.
+// The most specific entry in Agents is used, e.g. linux_arm64 before linux or arm64.
+func (c *JenkinsConfig) agent(target, goos, goarch string) string {
+	for _, k := range []string{target, goos, goarch} {
+		if l, exists := c.Agents[k]; exists {
+			return l
+		}
+	}
+	return ""
+}
+
+func (c *JenkinsConfig) logRotator(b jenkinsfile.Builder) {
+	b.Begin("buildDiscarder(").
+		Begin("logRotator(").
+		Array().
+		Property("artifactDaysToKeepStr", c.LogRotator.ArtifactDaysToKeep).
+		Property("artifactNumToKeepStr", c.LogRotator.ArtifactNumToKeep).
+		Property("daysToKeepStr", c.LogRotator.DaysToKeep).
+		Property("numToKeepStr", c.LogRotator.NumToKeep)
+}
+
+// options adds the options common to scripted & declarative pipelines
+func (c *JenkinsConfig) options(b jenkinsfile.Builder) {
+	c.logRotator(b)
+	if !c.ConcurrentBuilds {
+		b.Simple("disableConcurrentBuilds")
+	}
+	if !c.Resume {
+		b.Simple("disableResume")
+	}
+}
+
+func (c *JenkinsConfig) hasTriggers() bool {
+	t := c.Triggers
+	return t.Cron != "" || t.PollSCM != "" || t.Upstream != ""
+}
+
+func (c *JenkinsConfig) triggers(b jenkinsfile.Builder) {
+	t := c.Triggers
+	if t.Cron != "" {
+		b.Simple("cron", fmt.Sprintf("%q", t.Cron))
+	}
+	if t.PollSCM != "" {
+		b.Simple("pollSCM", fmt.Sprintf("%q", t.PollSCM))
+	}
+	if t.Upstream != "" {
+		threshold := strings.ToUpper(t.UpstreamThreshold)
+		if threshold == "" {
+			threshold = "SUCCESS"
+		}
+		b.Simple("upstream",
+			fmt.Sprintf("upstreamProjects: %q", t.Upstream),
+			"threshold: hudson.model.Result."+threshold)
+	}
+}
+
+func (c *JenkinsConfig) parameters(b jenkinsfile.Builder) {
+	for _, p := range c.Parameters {
+		name := fmt.Sprintf("name: %q", p.Name)
+		desc := fmt.Sprintf("description: %q", p.Description)
+
+		switch p.Type {
+		case "boolean":
+			b.Simple("booleanParam", name, "defaultValue: "+fmt.Sprint(p.Default == "true"), desc)
+		case "choice":
+			b.Simple("choice", name, "choices: ["+jenkinsfile.QuoteAll(p.Choices)+"]", desc)
+		case "text":
+			b.Simple("text", name, fmt.Sprintf("defaultValue: %q", p.Default), desc)
+		default:
+			b.Simple("string", name, fmt.Sprintf("defaultValue: %q", p.Default), desc)
+		}
+	}
+}
+
 type Jenkins func(builder, node jenkinsfile.Builder)
 
 func (a Jenkins) Do(builder, node jenkinsfile.Builder) {
@@ -44,3 +191,305 @@ func (l *JenkinsList) ForEach(f func(Jenkins)) {
 func (s *Build) Jenkins(seq int, ext Jenkins) {
 	s.jenkins.Add(seq, ext)
 }
+
+// JenkinsPost adds an extension which is run once the build has completed, successfully or not.
+// The node passed to the extension is within a finally block where currentBuild.currentResult is set.
+func (s *Build) JenkinsPost(seq int, ext Jenkins) {
+	s.jenkinsPost.Add(seq, ext)
+}
+
+// declarativeJenkinsfile writes the Jenkinsfile as a declarative pipeline
+func (s *Build) declarativeJenkinsfile(arches []arch.Arch) error {
+	builder := jenkinsfile.New().Declarative()
+
+	pipeline := builder.Pipeline(*s.BuildNode)
+
+	c := s.jenkinsConfig
+	c.configure(builder)
+
+	options := pipeline.Section("options")
+	c.options(options)
+	if c.Timeout > 0 {
+		options.Simple("timeout", fmt.Sprintf("time: %d, unit: 'MINUTES'", c.Timeout))
+	}
+
+	if len(c.Parameters) > 0 {
+		c.parameters(pipeline.Section("parameters"))
+	}
+
+	if c.hasTriggers() {
+		c.triggers(pipeline.Section("triggers"))
+	}
+
+	// Notify on completion is handled by the post extensions
+	if !s.jenkinsPost.IsEmpty() {
+		pipeline.Section("environment").
+			Line("NOTIFY = 'none'")
+	}
+
+	stages := pipeline.Section("stages")
+
+	stages.Stage("Init").
+		Sh("make clean init")
+
+	s.jenkinsTest(stages.Stage("Test"))
+
+	stashes := &jenkinsStashes{}
+	s.jenkinsNativeTests(stages, arches, stashes)
+	s.jenkinsFuzz(stages)
+
+	switch {
+	case *s.BuildLocal:
+		// Build against the local platform only
+		stages.Stage("Build").
+			Sh("make -f Makefile.gen all")
+
+	case *s.Parallelize:
+		// A matrix of each platform, built in parallel
+		s.jenkinsMatrix(stages, arches, stashes)
+
+	default:
+		// Each platform as a stage in sequence
+		targets := make(map[string]arch.Arch)
+		for _, a := range arches {
+			targets[a.Target()] = a
+		}
+		for _, t := range sortedKeys(targets) {
+			s.jenkinsBuildTarget(stages.Stage(t), targets[t], stashes)
+		}
+	}
+
+	s.jenkinsIntegration(stages)
+
+	stashes.packageStage(stages, *s.Encoder.Dest, *s.Dist, *s.ArchiveArtifacts == "")
+
+	s.jenkins.ForEach(func(e Jenkins) {
+		e.Do(builder, stages)
+	})
+
+	if *s.ArchiveArtifacts != "" {
+		stages.Stage("archiveArtifacts").
+			ArchiveArtifacts(*s.ArchiveArtifacts)
+	}
+
+	if !s.jenkinsPost.IsEmpty() {
+		always := pipeline.Section("post").Section("always")
+		s.jenkinsPost.ForEach(func(e Jenkins) {
+			e.Do(builder, always)
+		})
+	}
+
+	return os.WriteFile("Jenkinsfile", []byte(builder.Build()), 0644)
+}
+
+// jenkinsStashes are the stashes made on other agents, unstashed by the Package stage
+type jenkinsStashes struct {
+	dist  []string // Stashes of dist
+	tests []string // Stashes of test results
+}
+
+func (s *jenkinsStashes) IsEmpty() bool {
+	return len(s.dist) == 0 && len(s.tests) == 0
+}
+
+// packageStage adds the Package stage gathering the stashes together.
+// If archive is set then dist is archived, otherwise -build-archiveArtifacts does that later.
+func (s *jenkinsStashes) packageStage(node jenkinsfile.Builder, builds, dist string, archive bool) {
+	if s.IsEmpty() {
+		return
+	}
+
+	stage := node.Stage("Package")
+	for _, n := range s.dist {
+		stage.Unstash(n)
+	}
+	for _, t := range s.tests {
+		stage.Dir(filepath.Join(builds, "test", t)).
+			Unstash("test-" + t)
+	}
+
+	if archive && len(s.dist) > 0 {
+		stage.Line("archiveArtifacts artifacts: '%s/**', allowEmptyArchive: true", dist)
+	}
+}
+
+// jenkinsOnAgent returns the builder to add steps to a stage which runs on another agent.
+// The steps start with a checkout and init as the workspace is not shared.
+func jenkinsOnAgent(stage jenkinsfile.Builder, label string) jenkinsfile.Builder {
+	if stage.IsDeclarative() {
+		// Declarative pipelines checkout automatically
+		stage.Agent(label)
+	} else {
+		stage = stage.Node(label).
+			Line("checkout scm")
+	}
+	return stage.Sh("make clean init")
+}
+
+// jenkinsBuildTarget adds the steps to build a platform to a stage.
+// If the platform has its own agent then it is built there and dist stashed for the Package stage.
+func (s *Build) jenkinsBuildTarget(stage jenkinsfile.Builder, a arch.Arch, stashes *jenkinsStashes) {
+	t := a.Target()
+
+	label := s.jenkinsConfig.agent(t, a.GOOS, a.Arch())
+	if label == "" {
+		stage.Sh("make -f Makefile.gen " + t)
+		return
+	}
+
+	jenkinsOnAgent(stage, label).
+		Sh("make -f Makefile.gen "+t).
+		Stash(t, *s.Dist+"/**")
+	stashes.dist = append(stashes.dist, t)
+}
+
+// jenkinsTest adds the steps to run the tests and publish their results & coverage.
+// Test failures mark the build as unstable unless -go-test-fail fails the build.
+func (s *Build) jenkinsTest(stage jenkinsfile.Builder) {
+	builds := *s.Encoder.Dest
+	stage.Sh("make test").
+		JUnit(filepath.Join(builds, GoTestJUnit)).
+		RecordCoverage(filepath.Join(builds, GoCobertura))
+
+	jenkinsUnstable(stage, filepath.Join(builds, GoTestFailed), "Tests failed")
+}
+
+// jenkinsUnstable marks the build as unstable when a marker written on failure by markFailed exists
+func jenkinsUnstable(stage jenkinsfile.Builder, marker, message string) {
+	stage.Script().
+		Begin("if (fileExists('%s')) {", marker).
+		Line("unstable('%s')", message)
+}
+
+// jenkinsFuzz adds a Fuzz stage run only when the build was started by the cron trigger, e.g. nightly.
+// Crashers are archived and mark the build as unstable unless -go-test-fail fails the build.
+func (s *Build) jenkinsFuzz(node jenkinsfile.Builder) {
+	if !s.jenkinsConfig.Fuzz {
+		return
+	}
+
+	var stage jenkinsfile.Builder
+	if node.IsDeclarative() {
+		stage = node.Stage("Fuzz").
+			When("triggeredBy 'TimerTrigger'")
+	} else {
+		stage = node.Begin("%s", "if (currentBuild.getBuildCauses('hudson.triggers.TimerTrigger$TimerTriggerCause')) {").
+			Stage("Fuzz")
+	}
+
+	builds := *s.Encoder.Dest
+	stage.Sh("make fuzz").
+		Line("archiveArtifacts artifacts: '%s/**', allowEmptyArchive: true", filepath.Join(builds, GoFuzzDir))
+
+	jenkinsUnstable(stage, filepath.Join(builds, GoFuzzFailed), "Fuzzing found crashers")
+}
+
+// jenkinsIntegration adds an Integration stage running the integration tests against the built tools.
+// Test failures mark the build as unstable unless -go-test-fail fails the build.
+func (s *Build) jenkinsIntegration(node jenkinsfile.Builder) {
+	if !s.jenkinsConfig.Integration {
+		return
+	}
+
+	builds := *s.Encoder.Dest
+	stage := node.Stage("Integration").
+		Sh("make integration").
+		JUnit(filepath.Join(builds, GoIntegrationDir, GoTestJUnit))
+
+	jenkinsUnstable(stage, filepath.Join(builds, GoIntegrationFailed), "Integration tests failed")
+}
+
+// jenkinsNativeTests adds a stage for each target in NativeTests, running the tests on its agent.
+// The test results are stashed and placed under builds/test by the Package stage.
+func (s *Build) jenkinsNativeTests(node jenkinsfile.Builder, arches []arch.Arch, stashes *jenkinsStashes) {
+	for _, t := range s.jenkinsConfig.NativeTests {
+		label := ""
+		for _, a := range arches {
+			if a.Target() == t {
+				label = s.jenkinsConfig.agent(t, a.GOOS, a.Arch())
+			}
+		}
+
+		// Without an agent of its own run on the pipeline's agent
+		stage := node.Stage("Test " + t)
+		if label != "" {
+			stage = jenkinsOnAgent(stage, label)
+		}
+		s.jenkinsTest(stage)
+		stage.Dir(*s.Encoder.Dest).
+			Stash("test-"+t, GoTestOutput+","+GoTestJUnit+","+GoTestJSON+","+GoCobertura)
+		stashes.tests = append(stashes.tests, t)
+	}
+}
+
+// jenkinsMatrix adds a Build stage with a matrix of each platform.
+//
+// The axes are TARGET_GOOS and TARGET_GOARCH, not GOOS and GOARCH, as axes are
+// set in the environment which would change the platform go runs for.
+// TARGET_GOARCH includes the arm version, e.g. arm6, as in the make targets.
+// Combinations which are not supported are excluded.
+//
+// Every cell of a matrix runs on the same agent, so platforms with their own agent are built
+// in parallel by a following stage, stashing dist for the Package stage.
+func (s *Build) jenkinsMatrix(stages jenkinsfile.Builder, arches []arch.Arch, stashes *jenkinsStashes) {
+	supported := make(map[string]map[string]bool)
+	allArches := make(map[string]bool)
+	agents := make(map[string]arch.Arch)
+	for _, a := range arches {
+		if s.jenkinsConfig.agent(a.Target(), a.GOOS, a.Arch()) != "" {
+			agents[a.Target()] = a
+			continue
+		}
+
+		if supported[a.GOOS] == nil {
+			supported[a.GOOS] = make(map[string]bool)
+		}
+		supported[a.GOOS][a.Arch()] = true
+		allArches[a.Arch()] = true
+	}
+
+	if len(supported) > 0 {
+		goos := sortedKeys(supported)
+		goarch := sortedKeys(allArches)
+
+		matrix := stages.MatrixStage("Build").
+			Axis("TARGET_GOOS", goos...).
+			Axis("TARGET_GOARCH", goarch...)
+
+		for _, o := range goos {
+			var excluded []string
+			for _, a := range goarch {
+				if !supported[o][a] {
+					excluded = append(excluded, a)
+				}
+			}
+
+			if len(excluded) > 0 {
+				matrix.Exclude().
+					Axis("TARGET_GOOS", o).
+					Axis("TARGET_GOARCH", excluded...)
+			}
+		}
+
+		matrix.Section("stages").
+			Stage("Build").
+			Sh("make -f Makefile.gen ${TARGET_GOOS}_${TARGET_GOARCH}")
+	}
+
+	if len(agents) > 0 {
+		parallel := stages.Begin("stage(%q) {", "Build on agents").
+			Section("parallel")
+		for _, t := range sortedKeys(agents) {
+			s.jenkinsBuildTarget(parallel.Stage(t), agents[t], stashes)
+		}
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,239 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/arch"
+	"strings"
+	"testing"
+)
+
+func TestLoadJenkinsConfig(t *testing.T) {
+	b := newTestBuild(t, nil)
+
+	// Defaults without jenkins.yaml
+	if err := b.loadJenkinsConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if c := b.jenkinsConfig; c.LogRotator.NumToKeep != "10" || c.Triggers.Cron != "H H * * *" {
+		t.Errorf("defaults are numToKeep %q cron %q, want \"10\" \"H H * * *\"", c.LogRotator.NumToKeep, c.Triggers.Cron)
+	}
+
+	// jenkins.yaml overrides only what it sets
+	writeTestFile(t, "jenkins.yaml", "logRotator:\n  daysToKeep: \"7\"\ntriggers:\n  cron: \"\"\n")
+	if err := b.loadJenkinsConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if c := b.jenkinsConfig; c.LogRotator.NumToKeep != "10" || c.LogRotator.DaysToKeep != "7" || c.hasTriggers() {
+		t.Errorf("got %+v %+v", c.LogRotator, c.Triggers)
+	}
+
+	writeTestFile(t, "jenkins.yaml", "timeout: [\n")
+	if err := b.loadJenkinsConfig(); err == nil {
+		t.Error("no error from an invalid jenkins.yaml")
+	}
+}
+
+func TestJenkinsfile_Scripted(t *testing.T) {
+	b := newTestBuild(t, nil)
+	writeTestFile(t, "jenkins.yaml", `stages:
+  Test:
+    timeout: 30
+    retry: 2
+agents:
+  darwin: mac
+nativeTests:
+  - darwin_arm64
+  - linux_arm64
+`)
+	if err := b.jenkinsfile(testArches); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `properties([
+  buildDiscarder(
+    logRotator(
+      artifactDaysToKeepStr: '',
+      artifactNumToKeepStr: '',
+      daysToKeepStr: '',
+      numToKeepStr: '10'
+    )
+  ),
+  disableConcurrentBuilds(),
+  disableResume(),
+  pipelineTriggers([
+    cron("H H * * *")
+  ])
+])
+node("go") {
+  stage("Checkout") {
+    checkout scm
+  }
+  stage("Init") {
+    sh 'make clean init'
+  }
+  stage("Test") {
+    timeout(time: 30, unit: 'MINUTES') {
+      retry(2) {
+        sh 'make test'
+        junit allowEmptyResults: true, testResults: 'builds/go-test.xml'
+        recordCoverage tools: [[parser: 'COBERTURA', pattern: 'builds/coverage.xml']]
+        if (fileExists('builds/go-test.failed')) {
+          unstable('Tests failed')
+        }
+      }
+    }
+  }
+  stage("Test darwin_arm64") {
+    node("mac") {
+      checkout scm
+      sh 'make clean init'
+      sh 'make test'
+      junit allowEmptyResults: true, testResults: 'builds/go-test.xml'
+      recordCoverage tools: [[parser: 'COBERTURA', pattern: 'builds/coverage.xml']]
+      if (fileExists('builds/go-test.failed')) {
+        unstable('Tests failed')
+      }
+      dir("builds") {
+        stash name: "test-darwin_arm64", includes: "go-test.txt,go-test.xml,go-test.json,coverage.xml", allowEmpty: true
+      }
+    }
+  }
+  stage("Test linux_arm64") {
+    sh 'make test'
+    junit allowEmptyResults: true, testResults: 'builds/go-test.xml'
+    recordCoverage tools: [[parser: 'COBERTURA', pattern: 'builds/coverage.xml']]
+    if (fileExists('builds/go-test.failed')) {
+      unstable('Tests failed')
+    }
+    dir("builds") {
+      stash name: "test-linux_arm64", includes: "go-test.txt,go-test.xml,go-test.json,coverage.xml", allowEmpty: true
+    }
+  }
+  stage("darwin_arm64") {
+    node("mac") {
+      checkout scm
+      sh 'make clean init'
+      sh 'make -f Makefile.gen darwin_arm64'
+      stash name: "darwin_arm64", includes: "dist/**", allowEmpty: true
+    }
+  }
+  stage("linux_amd64") {
+    sh 'make -f Makefile.gen linux_amd64'
+  }
+  stage("linux_arm64") {
+    sh 'make -f Makefile.gen linux_arm64'
+  }
+  stage("Package") {
+    unstash "darwin_arm64"
+    dir("builds/test/darwin_arm64") {
+      unstash "test-darwin_arm64"
+    }
+    dir("builds/test/linux_arm64") {
+      unstash "test-linux_arm64"
+    }
+    archiveArtifacts artifacts: 'dist/**', allowEmptyArchive: true
+  }
+}`
+	if got := readTestFile(t, "Jenkinsfile"); got != want {
+		t.Errorf("Jenkinsfile =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJenkinsfile_DeclarativeMatrix(t *testing.T) {
+	b := newTestBuild(t, func(b *Build) {
+		*b.Declarative = true
+		*b.Parallelize = true
+	})
+	writeTestFile(t, "jenkins.yaml", "agents:\n  darwin: mac\n")
+
+	arches := append([]arch.Arch{{GOOS: "windows", GOARCH: "amd64"}}, testArches...)
+	if err := b.jenkinsfile(arches); err != nil {
+		t.Fatal(err)
+	}
+
+	// From the Build stage to the end, the stages before it are the same as without -build-parallel
+	got := readTestFile(t, "Jenkinsfile")
+	if i := strings.Index(got, "    stage(\"Build\") {"); i >= 0 {
+		got = got[i:]
+	}
+
+	want := `    stage("Build") {
+      matrix {
+        axes {
+          axis {
+            name "TARGET_GOOS"
+            values "linux", "windows"
+          }
+          axis {
+            name "TARGET_GOARCH"
+            values "amd64", "arm64"
+          }
+        }
+        excludes {
+          exclude {
+            axis {
+              name "TARGET_GOOS"
+              values "windows"
+            }
+            axis {
+              name "TARGET_GOARCH"
+              values "arm64"
+            }
+          }
+        }
+        stages {
+          stage("Build") {
+            steps {
+              sh 'make -f Makefile.gen ${TARGET_GOOS}_${TARGET_GOARCH}'
+            }
+          }
+        }
+      }
+    }
+    stage("Build on agents") {
+      parallel {
+        stage("darwin_arm64") {
+          agent {
+            label "mac"
+          }
+          steps {
+            sh 'make clean init'
+            sh 'make -f Makefile.gen darwin_arm64'
+            stash name: "darwin_arm64", includes: "dist/**", allowEmpty: true
+          }
+        }
+      }
+    }
+    stage("Package") {
+      steps {
+        unstash "darwin_arm64"
+        archiveArtifacts artifacts: 'dist/**', allowEmptyArchive: true
+      }
+    }
+  }
+}`
+	if got != want {
+		t.Errorf("Jenkinsfile =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJenkinsfile_DeclarativeMatrixWithoutAgents(t *testing.T) {
+	b := newTestBuild(t, func(b *Build) {
+		*b.Declarative = true
+		*b.Parallelize = true
+	})
+	if err := b.jenkinsfile(testArches); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readTestFile(t, "Jenkinsfile")
+	for _, s := range []string{`stage("Build on agents")`, `stage("Package")`, "agent {\n        label"} {
+		if strings.Contains(got, s) {
+			t.Errorf("unexpected %q in\n%s", s, got)
+		}
+	}
+	for _, s := range []string{`values "darwin", "linux"`, "excludes {"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("missing %q in\n%s", s, got)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/executor"
+	"github.com/peter-mount/go-build/util/jenkinsfile"
+	"github.com/peter-mount/go-build/util/makefile"
+	"github.com/peter-mount/go-build/util/makefile/target"
+	"github.com/peter-mount/go-build/util/meta"
+	"github.com/peter-mount/go-build/util/notify"
+	"github.com/peter-mount/go-build/util/publish"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Notify struct {
+	Encoder     *Encoder `kernel:"inject"`
+	Build       *Build   `kernel:"inject"`
+	Notify      *string  `kernel:"flag,notify,send build notification with status success|failure"`
+	NotifyStart *bool    `kernel:"flag,notify-start,record the start of the build for notifications"`
+	config      *NotifyConfig
+}
+
+// NotifyStartFile is written in the build directory by the init target with the time the build started
+const NotifyStartFile = ".build-start"
+
+// NotifyConfig is the content of notify.yaml
+type NotifyConfig struct {
+	Disable  bool      `yaml:"disable"`
+	Webhooks []Webhook `yaml:"webhooks"`
+}
+
+type Webhook struct {
+	URL    string   `yaml:"url"`
+	Format string   `yaml:"format"` // json or slack, default json
+	On     []string `yaml:"on"`     // Statuses to notify on, default all
+}
+
+// Accepts returns true if the webhook should be sent for a status
+func (w Webhook) Accepts(status string) bool {
+	if len(w.On) == 0 {
+		return true
+	}
+	for _, s := range w.On {
+		if strings.ToLower(s) == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Notify) Start() error {
+	if err := s.loadConfig(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if *s.NotifyStart {
+		return s.writeStart()
+	}
+
+	if s.config != nil && !s.config.Disable && len(s.config.Webhooks) > 0 {
+		s.Build.AddInit(s.init)
+		s.Build.AddCompletion(s.completion)
+		s.Build.AddRunCompletion(s.runCompletion)
+		s.Build.Makefile(110, s.makefile)
+		s.Build.JenkinsPost(100, s.jenkins)
+	}
+
+	// none is used to disable notifications from make when the caller handles them
+	if *s.Notify != "" && *s.Notify != "none" && s.config != nil && !s.config.Disable {
+		return s.run(nil)
+	}
+	return nil
+}
+
+// writeStart records the start of the build, used for the duration of the build
+func (s *Notify) writeStart() error {
+	return writeFile(filepath.Join(*s.Encoder.Dest, NotifyStartFile), time.Now().Format(time.RFC3339)+"\n")
+}
+
+func (s *Notify) loadConfig() error {
+	b, err := os.ReadFile("notify.yaml")
+	if err != nil {
+		return err
+	}
+
+	config := &NotifyConfig{}
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return err
+	}
+
+	// Allow webhook urls to be passed in from the environment
+	for i := range config.Webhooks {
+		util.ExpandEnvAll(&config.Webhooks[i].URL)
+	}
+	s.config = config
+	return nil
+}
+
+func (s *Notify) command() string {
+	return "@$(BUILD) -d " + *s.Encoder.Dest + " -dist " + *s.Build.Dist + " -notify $(or $(NOTIFY),success)"
+}
+
+// init records the start of the build.
+// The init target is run at the start of every make run so this is when the current run started.
+func (s *Notify) init(b makefile.Builder) makefile.Builder {
+	return b.Line("@$(BUILD) -d %s -notify-start", *s.Encoder.Dest)
+}
+
+// completion notifies once the all target has completed.
+// make stops when a platform fails so this is only run when the build succeeded,
+// failures are reported by CI with the notify target or by -run.
+func (s *Notify) completion(b makefile.Builder) makefile.Builder {
+	return b.Line("%s", s.command())
+}
+
+// makefile adds the notify target, used by CI to notify with a specific status
+func (s *Notify) makefile(_ makefile.Builder, target target.Builder, _ *meta.Meta) {
+	target.PhonyTarget("notify").
+		Line("%s", s.command())
+}
+
+// jenkins notifies once the pipeline has completed.
+// The duration is passed as later stages run init again.
+func (s *Notify) jenkins(_, post jenkinsfile.Builder) {
+	post.Line(`sh "BUILD_DURATION=${currentBuild.duration} make -f Makefile.gen notify NOTIFY=${currentBuild.currentResult} || true"`)
+}
+
+// runCompletion notifies when -run failed, the all target has already notified when it succeeded
+func (s *Notify) runCompletion(summary *executor.Summary) error {
+	if summary.OK() || *s.Notify == "none" {
+		return nil
+	}
+	*s.Notify = "failure"
+	return s.run(summary.Failures)
+}
+
+// run sends the notification, failures are the rules which failed if known
+func (s *Notify) run(failures []string) error {
+	payload, err := s.payload(strings.ToLower(*s.Notify), failures)
+	if err != nil {
+		return err
+	}
+
+	util.Label("NOTIFY", "%s", payload.Summary())
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	for _, w := range s.config.Webhooks {
+		if w.Accepts(payload.Status) {
+			// Don't fail the build because a webhook failed
+			if err := notify.Send(client, w.URL, w.Format, payload); err != nil {
+				util.Label("WARNING", "notify failed: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Notify) payload(status string, failures []string) (notify.Payload, error) {
+	p := notify.Payload{Status: status}
+
+	m, err := meta.New()
+	if err != nil {
+		return p, err
+	}
+	p.Project = m.PackageName
+	p.Version = m.Version
+	p.Duration = s.duration()
+
+	arches, err := arch.GetArches()
+	if err != nil {
+		return p, err
+	}
+
+	builds := *s.Encoder.Dest
+	for _, a := range arches {
+		// A platform failed if a rule for it failed, e.g. linux_amd64 or builds/linux/amd64/bin/tool
+		failed := false
+		for _, f := range failures {
+			target, _, _ := strings.Cut(f, ":")
+			failed = failed || target == a.Target() || strings.HasPrefix(target, a.Target()+"_") ||
+				strings.HasPrefix(target, a.BaseDir(builds)+string(filepath.Separator))
+		}
+
+		entries, err := os.ReadDir(filepath.Join(a.BaseDir(builds), "bin"))
+		switch {
+		case failed:
+			p.Failures = append(p.Failures, a.Platform())
+		case err == nil && len(entries) > 0:
+			p.Platforms = append(p.Platforms, a.Platform())
+		}
+	}
+
+	// Manifest of dist, ignore if dist does not exist e.g. build failed early
+	artifacts, _ := publish.Artifacts(*s.Build.Dist)
+	for _, a := range artifacts {
+		p.Artifacts = append(p.Artifacts, notify.Artifact{Name: a.Name, Size: a.Size, Sha256: a.Sha256})
+	}
+
+	return p, nil
+}
+
+// duration returns the duration of the build in seconds, 0 if not known.
+// BUILD_DURATION in milliseconds is passed by CI, otherwise it's from when init was last run.
+func (s *Notify) duration() float64 {
+	if ms, err := strconv.ParseInt(getEnv("BUILD_DURATION"), 10, 64); err == nil {
+		return (time.Duration(ms) * time.Millisecond).Round(time.Second).Seconds()
+	}
+
+	b, err := os.ReadFile(filepath.Join(*s.Encoder.Dest, NotifyStartFile))
+	if err != nil {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+	return time.Since(t).Round(time.Second).Seconds()
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/makefile"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestNotify returns a Notify for a Build from newTestBuild
+func newTestNotify(t *testing.T) *Notify {
+	b := newTestBuild(t, nil)
+	n := &Notify{Encoder: b.Encoder, Build: b}
+	initFlags(reflect.ValueOf(n).Elem())
+	return n
+}
+
+func TestNotify_Init(t *testing.T) {
+	n := newTestNotify(t)
+	n.Build.AddInit(n.init)
+
+	builder := makefile.New()
+	n.Build.init(builder)
+
+	want := "init: \n\t@mkdir -p builds dist\n\t@$(BUILD) -d builds -notify-start"
+	if got := builder.Build(); !strings.Contains(got, want) {
+		t.Errorf("init =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNotify_Duration(t *testing.T) {
+	n := newTestNotify(t)
+
+	if got := n.duration(); got != 0 {
+		t.Errorf("duration() without a start = %v, want 0", got)
+	}
+
+	// Recorded by the init target
+	*n.NotifyStart = true
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	start := filepath.Join("builds", NotifyStartFile)
+	// The start is recorded to the second
+	if got := n.duration(); got > 1 {
+		t.Errorf("duration() after start = %v, want 0", got)
+	}
+
+	writeTestFile(t, start, time.Now().Add(-2*time.Minute).Format(time.RFC3339))
+	if got := n.duration(); got < 120 || got > 121 {
+		t.Errorf("duration() = %v, want 120", got)
+	}
+
+	// CI passes the duration of the whole pipeline
+	t.Setenv("BUILD_DURATION", "90400")
+	if got := n.duration(); got != 90 {
+		t.Errorf("duration() with BUILD_DURATION = %v, want 90", got)
+	}
+}
+
+func TestNotify_Payload(t *testing.T) {
+	n := newTestNotify(t)
+	writeTestFile(t, "go.mod", "module example.com/m\n\ngo 1.24\n")
+
+	// linux/arm64 was built, linux/amd64 & darwin/arm64 failed
+	for _, dir := range []string{"builds/linux/arm64/bin", "builds/linux/amd64/bin"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile(t, "builds/linux/arm64/bin/hello", "")
+	writeTestFile(t, "builds/linux/amd64/bin/hello", "")
+
+	failures := []string{
+		"builds/linux/amd64/bin/world: exit status 1",
+		"darwin_arm64_dist: exit status 2",
+	}
+	p, err := n.payload("failure", failures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Project != "m" || p.Status != "failure" {
+		t.Errorf("payload() = %s %s", p.Project, p.Status)
+	}
+	if want := []string{"linux:arm64:"}; !slices.Equal(p.Platforms, want) {
+		t.Errorf("Platforms = %v, want %v", p.Platforms, want)
+	}
+	if want := []string{"darwin:arm64:", "linux:amd64:"}; !slices.Equal(p.Failures, want) {
+		t.Errorf("Failures = %v, want %v", p.Failures, want)
+	}
+
+	// Without failures, e.g. from the notify target, nothing is guessed
+	p, err = n.payload("failure", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"linux:amd64:", "linux:arm64:"}; !slices.Equal(p.Platforms, want) || p.Failures != nil {
+		t.Errorf("payload() = %v %v, want %v", p.Platforms, p.Failures, want)
+	}
+}
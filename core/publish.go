@@ -0,0 +1,286 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/changelog"
+	"github.com/peter-mount/go-build/util/gitlab"
+	"github.com/peter-mount/go-build/util/jenkinsfile"
+	"github.com/peter-mount/go-build/util/makefile"
+	"github.com/peter-mount/go-build/util/makefile/target"
+	"github.com/peter-mount/go-build/util/meta"
+	"github.com/peter-mount/go-build/util/publish"
+	"github.com/peter-mount/go-build/util/semver"
+	"github.com/peter-mount/go-build/util/woodpecker"
+	"github.com/peter-mount/go-build/util/workflow"
+	"gopkg.in/yaml.v2"
+	"os"
+)
+
+type Publish struct {
+	Build   *Build  `kernel:"inject"`
+	Publish *string `kernel:"flag,publish,publish dist to github|http|s3"`
+	config  *PublishConfig
+}
+
+// PublishConfig is the content of publish.yaml
+type PublishConfig struct {
+	Disable  bool        `yaml:"disable"`
+	Targets  []string    `yaml:"targets"`  // Publishers run by "make publish"
+	Path     string      `yaml:"path"`     // Template for the path of each artifact
+	Parallel int         `yaml:"parallel"` // Number of concurrent uploads, default 4
+	Retries  int         `yaml:"retries"`  // Number of retries on failure, default 3
+	HTTP     PublishHTTP `yaml:"http"`
+	S3       PublishS3   `yaml:"s3"`
+}
+
+type PublishHTTP struct {
+	URL      string `yaml:"url"`
+	WebDAV   bool   `yaml:"webdav"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type PublishS3 struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+	PathStyle bool   `yaml:"pathStyle"`
+}
+
+func (s *Publish) Start() error {
+	if err := s.loadConfig(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if s.config != nil && !s.config.Disable && len(s.config.Targets) > 0 {
+		s.Build.Makefile(100, s.makefile)
+		s.Build.Jenkins(100, s.jenkins)
+		s.Build.GitHubAction(100, s.gitHubAction)
+		s.Build.GitLab(100, s.gitLab)
+		s.Build.Woodpecker(100, s.woodpecker)
+	}
+
+	if *s.Publish != "" {
+		return s.run()
+	}
+	return nil
+}
+
+func (s *Publish) loadConfig() error {
+	b, err := os.ReadFile("publish.yaml")
+	if err != nil {
+		return err
+	}
+
+	config := &PublishConfig{Parallel: 4, Retries: 3}
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return err
+	}
+
+	// Allow the locations & credentials to be passed in from the environment
+	util.ExpandEnvAll(
+		&config.HTTP.URL, &config.HTTP.Username, &config.HTTP.Password,
+		&config.S3.Endpoint, &config.S3.Region, &config.S3.Bucket, &config.S3.AccessKey, &config.S3.SecretKey,
+	)
+	s.config = config
+	return nil
+}
+
+// makefile adds the publish target
+func (s *Publish) makefile(_ makefile.Builder, target target.Builder, _ *meta.Meta) {
+	t := target.PhonyTarget("publish")
+	for _, p := range s.config.Targets {
+		t.Line("@$(BUILD) -d %s -dist %s -publish %s", *s.Build.Encoder.Dest, *s.Build.Dist, p)
+	}
+}
+
+// jenkins adds a Publish stage which only runs when building a tag
+func (s *Publish) jenkins(_, node jenkinsfile.Builder) {
+	if node.IsDeclarative() {
+		node.Stage("Publish").
+			When(`tag "v*"`).
+			Sh("make -f Makefile.gen publish")
+		return
+	}
+
+	node.Begin("if (env.TAG_NAME) {").
+		Stage("Publish").
+		Sh("make -f Makefile.gen publish")
+}
+
+// gitHubAction adds a release job which only runs when building a v* tag
+func (s *Publish) gitHubAction(w *workflow.Workflow) {
+	job := workflow.NewJob("Release", "ubuntu-latest")
+	job.Needs = w.JobIds()
+	job.If = "startsWith(github.ref, 'refs/tags/v')"
+	job.Permissions = map[string]string{"contents": "write"}
+	job.Env = map[string]string{"GITHUB_TOKEN": "${{ secrets.GITHUB_TOKEN }}"}
+
+	job.Checkout().
+		Run("Init", "make init").
+		Uses("Download artifacts", "actions/download-artifact@v4", map[string]string{
+			"path":           "dist",
+			"pattern":        "dist-*",
+			"merge-multiple": "true",
+		}).
+		Run("Publish", "make -f Makefile.gen publish")
+
+	w.AddJob("release", job)
+}
+
+// gitLab adds a publish job which only runs when building a v* tag
+func (s *Publish) gitLab(p *gitlab.Pipeline) {
+	job := gitlab.NewJob("publish", "make -f Makefile.gen publish").
+		Need("init", "package")
+	job.Rules = []gitlab.Rule{gitlab.TagRule}
+
+	p.AddStage("publish").
+		AddJob("publish", job)
+}
+
+// woodpecker adds a publish step which only runs when building a v* tag
+func (s *Publish) woodpecker(p *woodpecker.Pipeline) {
+	step := GoStep("publish", "make -f Makefile.gen publish")
+	step.When = []woodpecker.When{woodpecker.TagEvent}
+	if len(p.Steps[len(p.Steps)-1].DependsOn) > 0 {
+		step.DependsOn = p.StepNames()
+	}
+
+	// Publish to the release on the forge running the pipeline, e.g. Forgejo or Gitea
+	for _, t := range s.config.Targets {
+		if t == "github" {
+			step.Env("GITHUB_TOKEN", woodpecker.Secret{FromSecret: "github_token"}).
+				Env("GITHUB_API_URL", "${CI_FORGE_URL}/api/v1").
+				Env("GITHUB_REPOSITORY", "${CI_REPO}")
+		}
+	}
+
+	p.AddStep(step)
+}
+
+func (s *Publish) run() error {
+	switch *s.Publish {
+	case "github":
+		return s.github()
+
+	case "http", "s3":
+		return s.upload()
+
+	default:
+		return fmt.Errorf("unknown publish target %q", *s.Publish)
+	}
+}
+
+// version returns the tag to publish, falling back to the version if HEAD is not tagged
+func (s *Publish) version(m *meta.Meta) string {
+	if m.Tag != "" {
+		return m.Tag
+	}
+	return m.Version
+}
+
+// github creates or updates the release for the current version
+// then uploads the contents of dist to it.
+func (s *Publish) github() error {
+	m, err := meta.New()
+	if err != nil {
+		return err
+	}
+
+	tag := s.version(m)
+
+	gh, err := publish.NewGitHub(publish.RepositoryFromModule(m.PackagePrefix))
+	if err != nil {
+		return err
+	}
+
+	artifacts, err := publish.WriteChecksums(*s.Build.Dist)
+	if err != nil {
+		return err
+	}
+
+	notes, err := changelog.New(tag)
+	if err != nil {
+		return err
+	}
+
+	release := publish.GitHubRelease{
+		TagName: tag,
+		Name:    tag,
+		Body:    notes.Markdown(),
+	}
+
+	if v, err := semver.Parse(tag); err == nil {
+		release.Prerelease = v.IsPreRelease()
+	}
+
+	return gh.Publish(release, artifacts)
+}
+
+// upload the contents of dist to a generic store configured in publish.yaml
+func (s *Publish) upload() error {
+	if s.config == nil {
+		return errors.New("publish.yaml is required to publish to " + *s.Publish)
+	}
+
+	var p publish.Publisher
+	switch *s.Publish {
+	case "http":
+		c := s.config.HTTP
+		if c.URL == "" {
+			return errors.New("http.url is required in publish.yaml")
+		}
+		h := publish.NewHTTP(c.URL)
+		h.WebDAV = c.WebDAV
+		h.Username = c.Username
+		h.Password = c.Password
+		p = h
+
+	case "s3":
+		c := s.config.S3
+		if c.Endpoint == "" || c.Bucket == "" {
+			return errors.New("s3.endpoint and s3.bucket are required in publish.yaml")
+		}
+		s3 := publish.NewS3(c.Endpoint, c.Region, c.Bucket)
+		s3.AccessKey = firstNonEmpty(c.AccessKey, os.Getenv("AWS_ACCESS_KEY_ID"))
+		s3.SecretKey = firstNonEmpty(c.SecretKey, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+		s3.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		s3.Region = firstNonEmpty(s3.Region, os.Getenv("AWS_REGION"), "us-east-1")
+		s3.PathStyle = c.PathStyle
+		p = s3
+	}
+
+	m, err := meta.New()
+	if err != nil {
+		return err
+	}
+
+	artifacts, err := publish.WriteChecksums(*s.Build.Dist)
+	if err != nil {
+		return err
+	}
+
+	uploader, err := publish.NewUploader(p, s.config.Path, s.config.Parallel, s.config.Retries)
+	if err != nil {
+		return err
+	}
+
+	return uploader.Upload(publish.PathData{
+		PackageName:   m.PackageName,
+		PackagePrefix: m.PackagePrefix,
+		Version:       s.version(m),
+	}, artifacts)
+}
+
+func firstNonEmpty(a ...string) string {
+	for _, s := range a {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
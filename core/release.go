@@ -0,0 +1,111 @@
+package core
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/changelog"
+	"github.com/peter-mount/go-build/util/meta"
+	"github.com/peter-mount/go-build/util/semver"
+)
+
+type Release struct {
+	Build     *Build  `kernel:"inject"`
+	Release   *string `kernel:"flag,release,release command e.g. bump"`
+	Generate  *bool   `kernel:"flag,release-generate,regenerate Jenkinsfile & platforms.md before tagging"`
+	Changelog *string `kernel:"flag,release-changelog,prepend release notes to this file before tagging"`
+}
+
+func (s *Release) Start() error {
+	if *s.Release != "" {
+		return s.run()
+	}
+	return nil
+}
+
+func (s *Release) run() error {
+	switch *s.Release {
+	case "bump":
+		args := flag.Args()
+		if len(args) != 1 {
+			return errors.New("-release bump major|minor|patch|pre")
+		}
+		return s.bump(args[0])
+
+	default:
+		return fmt.Errorf("unknown release command %q", *s.Release)
+	}
+}
+
+func (s *Release) bump(kind string) error {
+	dirty, err := meta.IsDirty()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return errors.New("working tree has uncommitted changes, refusing to release")
+	}
+
+	current := meta.ReleaseVersion()
+	if current == "" {
+		current = "v0.0.0"
+	}
+
+	version, err := semver.Parse(current)
+	if err != nil {
+		return err
+	}
+
+	next, err := version.Bump(kind)
+	if err != nil {
+		return err
+	}
+
+	tag := next.String()
+	util.Label("RELEASE", "%s -> %s", current, tag)
+
+	message := "Release " + tag
+
+	if *s.Generate {
+		util.Label("GENERATE", "Jenkinsfile platforms.md")
+		if err := s.Build.RegenerateProjectFiles(); err != nil {
+			return err
+		}
+	}
+
+	if *s.Changelog != "" {
+		release, err := changelog.New(tag)
+		if err != nil {
+			return err
+		}
+
+		util.Label("CHANGELOG", "%s", *s.Changelog)
+		if err := release.Prepend(*s.Changelog); err != nil {
+			return err
+		}
+
+		message = message + "\n\n" + release.Markdown()
+	}
+
+	// Commit anything we regenerated, the tree was clean before so this is only our changes
+	dirty, err = meta.IsDirty()
+	if err == nil && dirty {
+		util.Label("GIT", "commit Release %s", tag)
+		err = util.RunCommand("git", "add", "-A")
+		if err == nil {
+			err = util.RunCommand("git", "commit", "-q", "-m", "Release "+tag)
+		}
+	}
+
+	if err == nil {
+		util.Label("GIT", "tag %s", tag)
+		err = util.RunCommand("git", "tag", "-a", "--cleanup=verbatim", tag, "-m", message)
+	}
+
+	if err == nil {
+		fmt.Printf("Created tag %s, push it with: git push origin %s\n", tag, tag)
+	}
+
+	return err
+}
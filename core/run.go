@@ -0,0 +1,47 @@
+package core
+
+import (
+	"errors"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/executor"
+	"github.com/peter-mount/go-build/util/makefile"
+	"os"
+	"strings"
+)
+
+// run builds the requested targets with the native executor instead of make
+func (s *Build) run(builder makefile.Builder) error {
+	graph := makefile.NewGraph(builder)
+
+	// Call ourselves directly rather than the path the Makefile would use
+	if exe, err := os.Executable(); err == nil {
+		graph.Vars["BUILD"] = exe
+	}
+
+	ex := executor.New(graph)
+	if *s.RunJobs > 0 {
+		ex.Jobs = *s.RunJobs
+	}
+	ex.KeepGoing = *s.RunKeepGoing
+
+	summary, err := ex.Run(strings.Fields(*s.RunTargets)...)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range summary.Failures {
+		util.Label("FAILED", "%s", f)
+	}
+	util.Label("SUMMARY", "%s", summary)
+
+	for _, h := range s.runCompletion {
+		if err := h(summary); err != nil {
+			return err
+		}
+	}
+
+	if !summary.OK() {
+		return errors.New("build failed")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/justfile"
+	"github.com/peter-mount/go-build/util/makefile"
+	"github.com/peter-mount/go-build/util/ninja"
+	"github.com/peter-mount/go-build/util/taskfile"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// writeRunners writes the rules for the build tools requested instead of make
+func (s *Build) writeRunners(builder makefile.Builder) error {
+	if *s.Ninja == "" && *s.Taskfile == "" && *s.Justfile == "" {
+		return nil
+	}
+
+	graph := makefile.NewGraph(builder)
+
+	if *s.Ninja != "" {
+		if err := writeFile(*s.Ninja, s.ninja(graph)); err != nil {
+			return err
+		}
+	}
+
+	if *s.Taskfile != "" {
+		if err := writeFile(*s.Taskfile, taskfile.New(graph).Build()); err != nil {
+			return err
+		}
+	}
+
+	if *s.Justfile != "" {
+		// just has no up-to-date checks so leave them to -run
+		j := justfile.New(graph)
+		j.Run = strings.Join(append([]string{graph.Expand("$(BUILD)")}, runArgs(os.Args[1:])...), " ")
+		if err := writeFile(*s.Justfile, j.Build()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runArgs returns the arguments to run targets with -run, the same ones which generated the rules
+// without those writing files or running targets.
+func runArgs(args []string) []string {
+	var a []string
+	for i := 0; i < len(args); i++ {
+		name, _, value := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		switch name {
+		case "build", "build-ninja", "build-taskfile", "build-justfile", "run":
+			// Skip the value unless it was passed as -flag=value
+			if !value && i+1 < len(args) {
+				i++
+			}
+		default:
+			a = append(a, shellQuote(args[i]))
+		}
+	}
+	return append(a, "-run")
+}
+
+// shellQuote quotes an argument for the shell if required
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=:./,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ninja returns the rules as a ninja build file
+func (s *Build) ninja(graph *makefile.Graph) string {
+	// go build is already parallel so limit how many run at the same time
+	depth := runtime.NumCPU() / 2
+	if depth < 1 {
+		depth = 1
+	}
+
+	return ninja.New(graph).
+		AddPool("go", depth, isGoBuild).
+		Build()
+}
+
+// isGoBuild returns true if the rule compiles a tool
+func isGoBuild(n *makefile.Node) bool {
+	for _, c := range n.Commands {
+		if strings.Contains(c, " -go build ") {
+			return true
+		}
+	}
+	return false
+}
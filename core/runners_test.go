@@ -0,0 +1,37 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRunArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "justfile",
+			args: []string{"-build-justfile", "justfile", "-d", "builds", "-dist", "dist"},
+			want: []string{"-d", "builds", "-dist", "dist", "-run"},
+		},
+		{
+			name: "all outputs",
+			args: []string{"-build", "Makefile.gen", "-build-ninja=build.ninja", "-build-taskfile", "Taskfile.yml", "-build-justfile", "justfile", "-d", "builds", "-build-local"},
+			want: []string{"-d", "builds", "-build-local", "-run"},
+		},
+		{
+			name: "quoted",
+			args: []string{"-build-justfile", "justfile", "-build-platform", "", "-build-platform", "linux:amd64: linux:arm64:"},
+			want: []string{"-build-platform", "''", "-build-platform", "'linux:amd64: linux:arm64:'", "-run"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runArgs(tt.args); !slices.Equal(got, tt.want) {
+				t.Errorf("runArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
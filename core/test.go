@@ -0,0 +1,543 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"github.com/peter-mount/go-build/util/coverage"
+	"github.com/peter-mount/go-build/util/gotest"
+	"github.com/peter-mount/go-build/util/meta"
+	"github.com/peter-mount/go-kernel/v2/log"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Files written by the test command in the build directory
+const (
+	GoTestOutput   = "go-test.txt"    // Output as go test would print it
+	GoTestJUnit    = "go-test.xml"    // Results in JUnit format
+	GoTestJSON     = "go-test.json"   // Summary of the results
+	GoTestFailed   = "go-test.failed" // Present when tests failed but -go-test-fail was not set
+	GoTestCoverage = "coverage.out"   // Coverage profile
+	GoCobertura    = "coverage.xml"   // Coverage in Cobertura format
+	GoCoverageHTML = "coverage.html"  // Coverage rendered by go tool cover
+)
+
+// goTestSlowest is the number of the slowest tests to include in the summary
+const goTestSlowest = 10
+
+// TestConfig is the content of test.yaml, configuring how the tests are run
+type TestConfig struct {
+	Race        bool              `yaml:"race"`     // Run with the race detector
+	Short       bool              `yaml:"short"`    // Run with -short
+	Shuffle     string            `yaml:"shuffle"`  // on, off or the seed to shuffle the tests with
+	Count       int               `yaml:"count"`    // Run each test this many times
+	Retries     int               `yaml:"retries"`  // Run failed tests again up to this many times to detect flaky tests
+	Include     []string          `yaml:"include"`  // Packages to test, default all
+	Exclude     []string          `yaml:"exclude"`  // Packages not to test
+	Timeout     string            `yaml:"timeout"`  // Timeout of each package, e.g. 10m
+	Timeouts    map[string]string `yaml:"timeouts"` // Timeout by package, overriding Timeout
+	Coverage    CoverageConfig    `yaml:"coverage"`
+	Bench       BenchConfig       `yaml:"bench"`
+	Fuzz        FuzzConfig        `yaml:"fuzz"`
+	Integration IntegrationConfig `yaml:"integration"`
+	tags        string            // Build tags to test with, set by the integration tests
+}
+
+type CoverageConfig struct {
+	CoverPkg string             `yaml:"coverpkg"` // Packages to collect coverage for, default ./...
+	Mode     string             `yaml:"mode"`     // set, count or atomic, default go's
+	Total    float64            `yaml:"total"`    // Minimum total coverage as a percentage
+	Package  float64            `yaml:"package"`  // Minimum coverage of every package
+	Packages map[string]float64 `yaml:"packages"` // Minimum coverage by package, overriding Package
+	Dirs     []string           `yaml:"dirs"`     // GOCOVERDIR's of binaries built with go build -cover to merge
+}
+
+// loadTestConfig loads test.yaml, keeping the defaults if it does not exist
+func loadTestConfig() (*TestConfig, error) {
+	config := &TestConfig{
+		Coverage: CoverageConfig{CoverPkg: "./..."},
+		Bench: BenchConfig{
+			Pattern:   ".",
+			Count:     6,
+			Baseline:  "bench-baseline.txt",
+			Threshold: 5,
+			Alpha:     0.05,
+		},
+		Fuzz: FuzzConfig{
+			Pattern: "^Fuzz",
+			Time:    "30s",
+		},
+		Integration: IntegrationConfig{
+			Tags: "integration",
+		},
+	}
+
+	b, err := os.ReadFile("test.yaml")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, err
+	}
+
+	return config, yaml.Unmarshal(b, config)
+}
+
+func (s *Go) test() error {
+	config, err := loadTestConfig()
+	if err != nil {
+		return err
+	}
+	s.testFlags(config)
+
+	module, err := s.module()
+	if err != nil {
+		return err
+	}
+
+	dest := *s.Encoder.Dest
+	coverOut := filepath.Join(dest, GoTestCoverage)
+	failed := filepath.Join(dest, GoTestFailed)
+
+	if err := removeMarker(failed); err != nil {
+		return err
+	}
+
+	groups, err := config.groups(module)
+	if err != nil {
+		return err
+	}
+
+	util.Label("GO TEST", "%s", filepath.Join(dest, GoTestOutput))
+
+	var profiles []string
+	report, exitCode, err := config.runTests(groups, func(i int) []string {
+		profile := coverOut
+		if len(groups) > 1 {
+			profile = filepath.Join(dest, fmt.Sprintf("coverage-%d.out", i))
+		}
+		profiles = append(profiles, profile)
+
+		args := []string{"-coverprofile=" + profile}
+		if config.Coverage.CoverPkg != "" {
+			args = append(args, "-coverpkg="+config.Coverage.CoverPkg)
+		}
+		if config.Coverage.Mode != "" {
+			args = append(args, "-covermode="+config.Coverage.Mode)
+		}
+		return args
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.reportTests(report, exitCode, dest, failed); err != nil {
+		return err
+	}
+
+	if len(profiles) > 1 {
+		if err := mergeProfiles(coverOut, profiles); err != nil {
+			return err
+		}
+	}
+
+	return s.coverage(config.Coverage, module)
+}
+
+// runTests runs go test -json over the groups of packages, then runs any failed tests again.
+// groupArgs returns additional arguments for a group.
+// Returns the report and exit code of go test, or an error if go test failed for any other reason than a test failing.
+func (c *TestConfig) runTests(groups []testGroup, groupArgs func(int) []string) (*gotest.Report, int, error) {
+	start := time.Now()
+	report := gotest.NewReport()
+
+	// Record the seed so a shuffled run can be repeated
+	report.Seed = c.Shuffle
+	if report.Seed == "on" {
+		report.Seed = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	if report.Seed == "off" {
+		report.Seed = ""
+	}
+
+	exitCode := 0
+	for i, g := range groups {
+		var args []string
+		if groupArgs != nil {
+			args = groupArgs(i)
+		}
+		if report.Seed != "" {
+			args = append(args, "-shuffle="+report.Seed)
+		}
+		if c.Count > 0 {
+			args = append(args, "-count="+strconv.Itoa(c.Count))
+		}
+		args = append(args, c.args(g.timeout)...)
+
+		code, err := goTestJSON(report, append(args, g.packages...))
+		if err != nil {
+			return nil, 0, err
+		}
+		if code != 0 {
+			exitCode = code
+		}
+	}
+
+	// A failure which is not from a test, e.g. an invalid flag, cannot be run again
+	if !report.Failed() {
+		if exitCode != 0 {
+			fmt.Println(report.Output.String())
+			return nil, 0, fmt.Errorf("go test returned %d", exitCode)
+		}
+		report.Elapsed = time.Since(start)
+		return report, exitCode, nil
+	}
+
+	err := c.retry(report, groups)
+	report.Elapsed = time.Since(start)
+	return report, exitCode, err
+}
+
+// reportTests writes the reports of a run of the tests to dest, then prints the summary and any failures
+func (s *Go) reportTests(report *gotest.Report, exitCode int, dest, marker string) error {
+	summary := report.Summary(goTestSlowest)
+
+	if err := writeTestReports(dest, report, summary); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if err := summary.WriteTable(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	if !report.Failed() {
+		return nil
+	}
+
+	fmt.Printf("Tests returned %d\n", exitCode)
+	if err := report.WriteFailures(os.Stdout); err != nil {
+		return err
+	}
+	return s.markFailed("GO TEST", marker, summary.Failed, "tests")
+}
+
+// removeMarker removes a marker written by markFailed by a previous run
+func removeMarker(marker string) error {
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// markFailed handles failures of the tests, benchmarks or fuzzing.
+// The build only fails with -go-test-fail, otherwise the marker is written so CI can flag the build as unstable.
+func (s *Go) markFailed(label, marker string, failures int, what string) error {
+	if failures == 0 {
+		return nil
+	}
+	if *s.FailTests {
+		return fmt.Errorf("%d %s failed", failures, what)
+	}
+	util.Label(label, "%s", marker)
+	return os.WriteFile(marker, []byte(strconv.Itoa(failures)+"\n"), 0644)
+}
+
+// testFlags overrides test.yaml with the flags which have been set
+func (s *Go) testFlags(config *TestConfig) {
+	config.Race = config.Race || *s.Race
+	config.Short = config.Short || *s.Short
+	if *s.Shuffle != "" {
+		config.Shuffle = *s.Shuffle
+	}
+	if *s.Count > 0 {
+		config.Count = *s.Count
+	}
+	if *s.Retries > 0 {
+		config.Retries = *s.Retries
+	}
+	if *s.Include != "" {
+		config.Include = strings.Split(*s.Include, ",")
+	}
+	if *s.Exclude != "" {
+		config.Exclude = strings.Split(*s.Exclude, ",")
+	}
+	if *s.Timeout != "" {
+		config.Timeout = *s.Timeout
+	}
+}
+
+// module returns the module's package prefix from go.mod
+func (s *Go) module() (string, error) {
+	m, err := meta.New()
+	if err != nil {
+		return "", err
+	}
+	return m.PackagePrefix, nil
+}
+
+// testGroup is a set of packages tested together with the same timeout
+type testGroup struct {
+	timeout  string
+	packages []string
+}
+
+// groups returns the packages to test, grouped by their timeout
+func (c *TestConfig) groups(module string) ([]testGroup, error) {
+	if len(c.Include) == 0 && len(c.Exclude) == 0 && len(c.Timeouts) == 0 {
+		return []testGroup{{timeout: c.Timeout, packages: []string{"./..."}}}, nil
+	}
+
+	out, err := exec.Command("go", "list", "./...").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	packages := gotest.FilterPackages(strings.Fields(string(out)), c.Include, c.Exclude, module)
+	if len(packages) == 0 {
+		return nil, errors.New("no packages to test")
+	}
+
+	var groups []testGroup
+	for _, pkg := range packages {
+		timeout := c.timeout(module, pkg)
+
+		i := slices.IndexFunc(groups, func(g testGroup) bool { return g.timeout == timeout })
+		if i < 0 {
+			i = len(groups)
+			groups = append(groups, testGroup{timeout: timeout})
+		}
+		groups[i].packages = append(groups[i].packages, pkg)
+	}
+	return groups, nil
+}
+
+// timeout returns the timeout of a package, using the longest pattern in Timeouts which matches it
+func (c *TestConfig) timeout(module, pkg string) string {
+	timeout, longest := c.Timeout, -1
+	for pattern, t := range c.Timeouts {
+		if gotest.MatchPackage(pattern, module, pkg) && len(pattern) > longest {
+			timeout, longest = t, len(pattern)
+		}
+	}
+	return timeout
+}
+
+// args returns the arguments common to running and retrying the tests
+func (c *TestConfig) args(timeout string) []string {
+	var args []string
+	if c.Race {
+		args = append(args, "-race")
+	}
+	if c.Short {
+		args = append(args, "-short")
+	}
+	if c.tags != "" {
+		args = append(args, "-tags="+c.tags)
+	}
+	if timeout != "" {
+		args = append(args, "-timeout="+timeout)
+	}
+	return args
+}
+
+// retry runs the failed tests again up to Retries times, marking those which pass as flaky
+func (c *TestConfig) retry(report *gotest.Report, groups []testGroup) error {
+	for i := 0; i < c.Retries; i++ {
+		failed := report.FailedTests()
+		if len(failed) == 0 {
+			return nil
+		}
+
+		rerun := gotest.NewReport()
+		for _, pkg := range sortedKeys(failed) {
+			util.Label("GO RETRY", "%s %s", pkg, strings.Join(failed[pkg], " "))
+
+			// A package from ./... is in the only group
+			timeout := groups[0].timeout
+			for _, g := range groups {
+				if slices.Contains(g.packages, pkg) {
+					timeout = g.timeout
+				}
+			}
+
+			args := append([]string{"-count=1", "-run=^(" + strings.Join(failed[pkg], "|") + ")$"}, c.args(timeout)...)
+			if _, err := goTestJSON(rerun, append(args, pkg)); err != nil {
+				return err
+			}
+		}
+
+		report.MarkFlaky(rerun)
+	}
+	return nil
+}
+
+// goTestJSON runs go test -json adding its output to a report, returning the exit code
+func goTestJSON(report *gotest.Report, args []string) (int, error) {
+	return goTestJSONEnv(report, nil, args)
+}
+
+// goTestJSONEnv runs go test -json with additional environment variables
+func goTestJSONEnv(report *gotest.Report, env, args []string) (int, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command("go", append([]string{"test", "-json"}, args...)...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = &buf
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = &buf
+
+	if log.IsVerbose() {
+		log.Println(cmd.String())
+	}
+
+	code := 0
+	if err := cmd.Run(); err != nil {
+		exit, ok := err.(*exec.ExitError)
+		if !ok {
+			return 0, err
+		}
+		code = exit.ExitCode()
+	}
+
+	return code, report.Read(&buf)
+}
+
+// mergeProfiles merges coverage profiles into one
+func mergeProfiles(name string, profiles []string) error {
+	merged := coverage.NewProfile("")
+	for _, n := range profiles {
+		p, err := coverage.ReadProfile(n)
+		if err != nil {
+			// No profile if no package could be tested
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if err := merged.Merge(p); err != nil {
+			return err
+		}
+	}
+	return writeReport(name, merged.Write)
+}
+
+// writeTestReports writes the test output, JUnit results and summary to a directory
+func writeTestReports(dest string, report *gotest.Report, summary *gotest.Summary) error {
+	if err := os.WriteFile(filepath.Join(dest, GoTestOutput), []byte(report.Output.String()), 0644); err != nil {
+		return err
+	}
+
+	if err := writeReport(filepath.Join(dest, GoTestJUnit), report.WriteJUnit); err != nil {
+		return err
+	}
+
+	return writeReport(filepath.Join(dest, GoTestJSON), summary.WriteJSON)
+}
+
+// coverage merges the coverage from go test with any from binaries built with -cover,
+// writes it as html & Cobertura, then checks it against the thresholds
+func (s *Go) coverage(config CoverageConfig, module string) error {
+	dest := *s.Encoder.Dest
+	coverOut := filepath.Join(dest, GoTestCoverage)
+
+	// No profile if no package could be tested
+	profile, err := coverage.ReadProfile(coverOut)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	merged, err := s.mergeCoverDirs(profile, config.Dirs)
+	if err != nil {
+		return err
+	}
+
+	// Write the merged profile back so go tool cover sees it
+	if merged {
+		if err := writeReport(coverOut, profile.Write); err != nil {
+			return err
+		}
+	}
+
+	source, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if err := writeReport(filepath.Join(dest, GoCobertura), func(w io.Writer) error {
+		return profile.WriteCobertura(w, module, source)
+	}); err != nil {
+		return err
+	}
+
+	html := filepath.Join(dest, GoCoverageHTML)
+	util.Label("GO REPORT", "%s", html)
+	if err := util.RunCommand("go", "tool", "cover", "-html="+coverOut, "-o", html); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if err := profile.WriteTable(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	return profile.Check(config.thresholds(module))
+}
+
+// mergeCoverDirs merges the coverage written to GOCOVERDIR by binaries built with go build -cover.
+// Each directory is converted separately as binaries built with -race use a different mode to those without.
+// Directories which do not exist or are empty are ignored.
+func (s *Go) mergeCoverDirs(profile *coverage.Profile, dirs []string) (bool, error) {
+	merged := false
+	for i, dir := range dirs {
+		if entries, err := os.ReadDir(dir); err != nil || len(entries) == 0 {
+			continue
+		}
+
+		out := filepath.Join(*s.Encoder.Dest, fmt.Sprintf("coverage-dir-%d.out", i))
+		util.Label("GO COVDATA", "%s", dir)
+		if err := util.RunCommand("go", "tool", "covdata", "textfmt", "-i="+dir, "-o", out); err != nil {
+			return false, err
+		}
+
+		p, err := coverage.ReadProfile(out)
+		if err != nil {
+			return false, err
+		}
+		if err := profile.Merge(p); err != nil {
+			return false, err
+		}
+		merged = true
+	}
+	return merged, nil
+}
+
+// thresholds returns the coverage thresholds, with package names relative to the module made absolute
+func (c CoverageConfig) thresholds(module string) coverage.Thresholds {
+	t := coverage.Thresholds{
+		Total:    c.Total,
+		Package:  c.Package,
+		Packages: make(map[string]float64),
+	}
+
+	for name, v := range c.Packages {
+		t.Packages[gotest.ResolvePackage(name, module)] = v
+	}
+
+	return t
+}
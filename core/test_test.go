@@ -0,0 +1,22 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestMergeProfiles(t *testing.T) {
+	newTestBuild(t, nil)
+
+	// A group run without -race in set mode, another with -race in atomic mode and one with no packages
+	writeTestFile(t, "plain.out", "mode: set\nexample.com/m/a.go:1.1,2.2 1 1\n")
+	writeTestFile(t, "race.out", "mode: atomic\nexample.com/m/a.go:1.1,2.2 1 3\nexample.com/m/b.go:1.1,2.2 1 0\n")
+
+	if err := mergeProfiles("coverage.out", []string{"plain.out", "race.out", "missing.out"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "mode: atomic\nexample.com/m/a.go:1.1,2.2 1 4\nexample.com/m/b.go:1.1,2.2 1 0\n"
+	if got := readTestFile(t, "coverage.out"); got != want {
+		t.Errorf("mergeProfiles() =\n%s\nwant\n%s", got, want)
+	}
+}
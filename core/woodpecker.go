@@ -0,0 +1,114 @@
+package core
+
+import (
+	"github.com/peter-mount/go-build/util/arch"
+	"github.com/peter-mount/go-build/util/meta"
+	"github.com/peter-mount/go-build/util/woodpecker"
+	"sort"
+)
+
+// WoodpeckerFile is where the Woodpecker pipeline is written
+const WoodpeckerFile = ".woodpecker.yml"
+
+// GoModCache is where go keeps downloaded modules in the golang image
+const GoModCache = "/go/pkg/mod"
+
+type Woodpecker func(p *woodpecker.Pipeline)
+
+func (a Woodpecker) Do(p *woodpecker.Pipeline) {
+	if a != nil {
+		a(p)
+	}
+}
+
+type WoodpeckerList []woodpeckerEntry
+
+type woodpeckerEntry struct {
+	seq   int
+	entry Woodpecker
+}
+
+func (l *WoodpeckerList) Add(seq int, entry Woodpecker) {
+	*l = append(*l, woodpeckerEntry{
+		seq:   seq,
+		entry: entry,
+	})
+}
+
+func (l *WoodpeckerList) ForEach(f func(Woodpecker)) {
+	sort.SliceStable(*l, func(i, j int) bool {
+		return (*l)[i].seq < (*l)[j].seq
+	})
+
+	for _, e := range *l {
+		f(e.entry)
+	}
+}
+
+// Woodpecker adds an extension to the Woodpecker pipeline.
+// Extensions are run once the build steps have been added.
+func (s *Build) Woodpecker(seq int, ext Woodpecker) {
+	s.woodpecker.Add(seq, ext)
+}
+
+// GoStep returns a Woodpecker step running in the golang image for the go version in go.mod.
+// Downloaded modules are kept in a volume so they are shared between steps and builds.
+func GoStep(name string, commands ...string) *woodpecker.Step {
+	image := "golang"
+	if v := meta.GoVersion(); v != "" {
+		image = image + ":" + v
+	}
+
+	step := woodpecker.NewStep(name, image, commands...).
+		Env("GOMODCACHE", GoModCache)
+	step.Volumes = []string{"go-mod-cache:" + GoModCache}
+	return step
+}
+
+// woodpeckerPipeline writes the Woodpecker pipeline
+func (s *Build) woodpeckerPipeline(arches []arch.Arch) error {
+	p := woodpecker.New()
+
+	test := GoStep("test", "make -f Makefile.gen test")
+	p.AddStep(GoStep("init", "make clean init")).
+		AddStep(test)
+
+	if *s.BuildLocal {
+		// Build against the local platform only
+		p.AddStep(GoStep("build", "make -f Makefile.gen all"))
+	} else {
+		// A step per OS, run in parallel with -build-parallel otherwise in sequence
+		var osList []string
+		oses := make(map[string]bool)
+		for _, a := range arches {
+			if !oses[a.GOOS] {
+				oses[a.GOOS] = true
+				osList = append(osList, a.GOOS)
+			}
+		}
+		sort.Strings(osList)
+
+		for _, goos := range osList {
+			step := GoStep(goos, "make -f Makefile.gen "+goos)
+			if *s.Parallelize {
+				step.DependsOn = []string{"test"}
+			}
+			p.AddStep(step)
+		}
+
+		// Once one step has depends_on they all need it
+		if *s.Parallelize {
+			test.DependsOn = []string{"init"}
+		}
+	}
+
+	s.woodpecker.ForEach(func(e Woodpecker) {
+		e.Do(p)
+	})
+
+	out, err := p.Build()
+	if err != nil {
+		return err
+	}
+	return writeFile(WoodpeckerFile, out)
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestWoodpeckerPipeline(t *testing.T) {
+	const header = `# Generated Woodpecker pipeline
+when:
+- event:
+  - push
+  - tag
+  - pull_request
+  - manual
+steps:
+`
+
+	tests := []struct {
+		name     string
+		local    bool
+		parallel bool
+		want     string
+	}{
+		{
+			name: "sequential",
+			want: header +
+				woodpeckerStep("init", "make clean init", "") +
+				woodpeckerStep("test", "make -f Makefile.gen test", "") +
+				woodpeckerStep("darwin", "make -f Makefile.gen darwin", "") +
+				woodpeckerStep("linux", "make -f Makefile.gen linux", ""),
+		},
+		{
+			name:     "parallel",
+			parallel: true,
+			want: header +
+				woodpeckerStep("init", "make clean init", "") +
+				woodpeckerStep("test", "make -f Makefile.gen test", "init") +
+				woodpeckerStep("darwin", "make -f Makefile.gen darwin", "test") +
+				woodpeckerStep("linux", "make -f Makefile.gen linux", "test"),
+		},
+		{
+			name:     "local",
+			local:    true,
+			parallel: true,
+			want: header +
+				woodpeckerStep("init", "make clean init", "") +
+				woodpeckerStep("test", "make -f Makefile.gen test", "") +
+				woodpeckerStep("build", "make -f Makefile.gen all", ""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBuild(t, func(b *Build) {
+				*b.BuildLocal = tt.local
+				*b.Parallelize = tt.parallel
+			})
+			writeTestFile(t, "go.mod", "module example.com/m\n\ngo 1.24\n")
+
+			if err := b.woodpeckerPipeline(testArches); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, WoodpeckerFile); got != tt.want {
+				t.Errorf("woodpeckerPipeline() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// woodpeckerStep returns the yaml of a step created by GoStep
+func woodpeckerStep(name, command, dependsOn string) string {
+	s := `- name: ` + name + `
+  image: golang:1.24
+  environment:
+    GOMODCACHE: /go/pkg/mod
+  volumes:
+  - go-mod-cache:/go/pkg/mod
+  commands:
+  - ` + command + "\n"
+	if dependsOn != "" {
+		s += "  depends_on:\n  - " + dependsOn + "\n"
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package arch
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// native lists the other architectures each architecture can run natively
+var native = map[string][]string{
+	"amd64": {"386"},
+}
+
+// nativeOS lists the operating systems which support running the native architectures,
+// e.g. darwin dropped 32 bit support
+var nativeOS = map[string]bool{
+	"linux":   true,
+	"windows": true,
+	"freebsd": true,
+	"netbsd":  true,
+	"openbsd": true,
+}
+
+// qemu maps the name of a qemu-user binfmt_misc entry to GOARCH
+var qemu = map[string]string{
+	"qemu-i386":        "386",
+	"qemu-x86_64":      "amd64",
+	"qemu-arm":         "arm",
+	"qemu-aarch64":     "arm64",
+	"qemu-loongarch64": "loong64",
+	"qemu-mips":        "mips",
+	"qemu-mipsel":      "mipsle",
+	"qemu-mips64":      "mips64",
+	"qemu-mips64el":    "mips64le",
+	"qemu-ppc64":       "ppc64",
+	"qemu-ppc64le":     "ppc64le",
+	"qemu-riscv64":     "riscv64",
+	"qemu-s390x":       "s390x",
+}
+
+// CanExecute returns true if binaries for the platform can be run on the host,
+// either natively or because binfmt, the GOARCH values from Binfmt, will run them under an emulator.
+func (a Arch) CanExecute(host Arch, binfmt map[string]bool) bool {
+	if a.GOOS != host.GOOS {
+		return false
+	}
+
+	if a.GOARCH == host.GOARCH {
+		return true
+	}
+
+	if nativeOS[a.GOOS] {
+		for _, n := range native[host.GOARCH] {
+			if n == a.GOARCH {
+				return true
+			}
+		}
+	}
+
+	return a.GOOS == "linux" && binfmt[a.GOARCH]
+}
+
+// Binfmt returns the GOARCH values of the enabled qemu-user emulators registered with binfmt_misc on linux
+func Binfmt() map[string]bool {
+	return binfmt("/proc/sys/fs/binfmt_misc")
+}
+
+func binfmt(dir string) map[string]bool {
+	arches := make(map[string]bool)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return arches
+	}
+
+	for _, e := range entries {
+		goarch, found := qemu[strings.TrimSuffix(e.Name(), "-static")]
+		if found && binfmtEnabled(filepath.Join(dir, e.Name())) {
+			arches[goarch] = true
+		}
+	}
+	return arches
+}
+
+// binfmtEnabled returns true if a binfmt_misc entry is enabled, its first line
+func binfmtEnabled(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	return scanner.Scan() && strings.TrimSpace(scanner.Text()) == "enabled"
+}
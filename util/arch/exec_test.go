@@ -0,0 +1,54 @@
+package arch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArch_CanExecute(t *testing.T) {
+	host := Arch{GOOS: "linux", GOARCH: "amd64"}
+	binfmt := map[string]bool{"arm64": true}
+
+	tests := []struct {
+		arch Arch
+		want bool
+	}{
+		{Arch{GOOS: "linux", GOARCH: "amd64"}, true},
+		{Arch{GOOS: "linux", GOARCH: "386"}, true},
+		{Arch{GOOS: "linux", GOARCH: "arm64"}, true},
+		{Arch{GOOS: "linux", GOARCH: "arm", GOARM: "7"}, false},
+		{Arch{GOOS: "windows", GOARCH: "amd64"}, false},
+		{Arch{GOOS: "darwin", GOARCH: "386"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arch.Target(), func(t *testing.T) {
+			if got := tt.arch.CanExecute(host, binfmt); got != tt.want {
+				t.Errorf("CanExecute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	darwin := Arch{GOOS: "darwin", GOARCH: "amd64"}
+	if (Arch{GOOS: "darwin", GOARCH: "386"}).CanExecute(darwin, nil) {
+		t.Error("darwin cannot run 386")
+	}
+}
+
+func TestBinfmt(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"qemu-arm":     "enabled\ninterpreter /usr/bin/qemu-arm\n",
+		"qemu-aarch64": "disabled\ninterpreter /usr/bin/qemu-aarch64\n",
+		"status":       "enabled\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := binfmt(dir)
+	if len(got) != 1 || !got["arm"] {
+		t.Errorf("got %v, want arm", got)
+	}
+}
@@ -0,0 +1,98 @@
+package bench
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Results are the results of benchmarks, in the format go test -bench writes
+type Results struct {
+	Benchmarks []*Benchmark
+	benchmarks map[string]*Benchmark
+}
+
+// Benchmark are the values of each run of a benchmark
+type Benchmark struct {
+	Package string
+	Name    string               // Name without the GOMAXPROCS suffix so results from different machines compare
+	Values  map[string][]float64 // Values of each run by unit, e.g. ns/op
+}
+
+// Key identifies the benchmark
+func (b *Benchmark) Key() string {
+	return b.Package + " " + b.Name
+}
+
+// Units returns the units of the benchmark, sorted
+func (b *Benchmark) Units() []string {
+	var units []string
+	for u := range b.Values {
+		units = append(units, u)
+	}
+	sort.Strings(units)
+	return units
+}
+
+var procs = regexp.MustCompile(`-\d+$`)
+
+// ReadFile reads the results from a file
+func ReadFile(name string) (*Results, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads the output of go test -bench.
+// Other lines, e.g. goos: or PASS, are ignored except pkg: which sets the package of the following benchmarks.
+func Parse(r io.Reader) (*Results, error) {
+	res := &Results{benchmarks: make(map[string]*Benchmark)}
+	pkg := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		switch {
+		case len(f) == 2 && f[0] == "pkg:":
+			pkg = f[1]
+
+		// BenchmarkName-8  1000  1234 ns/op  56 B/op  2 allocs/op
+		case len(f) >= 4 && len(f)%2 == 0 && strings.HasPrefix(f[0], "Benchmark"):
+			if _, err := strconv.Atoi(f[1]); err != nil {
+				continue
+			}
+
+			b := res.get(pkg, procs.ReplaceAllString(f[0], ""))
+			for i := 2; i < len(f); i += 2 {
+				if v, err := strconv.ParseFloat(f[i], 64); err == nil {
+					b.Values[f[i+1]] = append(b.Values[f[i+1]], v)
+				}
+			}
+		}
+	}
+
+	return res, scanner.Err()
+}
+
+func (r *Results) get(pkg, name string) *Benchmark {
+	k := pkg + " " + name
+	b := r.benchmarks[k]
+	if b == nil {
+		b = &Benchmark{Package: pkg, Name: name, Values: make(map[string][]float64)}
+		r.benchmarks[k] = b
+		r.Benchmarks = append(r.Benchmarks, b)
+	}
+	return b
+}
+
+// Get returns a benchmark or nil if it does not exist
+func (r *Results) Get(key string) *Benchmark {
+	return r.benchmarks[key]
+}
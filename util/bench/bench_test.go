@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testOutput = `goos: linux
+goarch: amd64
+pkg: example.com/a
+cpu: Intel(R) Core(TM) i7
+BenchmarkParse-8         	  100000	      1200 ns/op	     256 B/op	       4 allocs/op
+BenchmarkParse-8         	  100000	      1100 ns/op	     256 B/op	       4 allocs/op
+BenchmarkParse/small-8   	 1000000	       120 ns/op
+PASS
+ok  	example.com/a	3.2s
+pkg: example.com/b
+BenchmarkParse-16        	   50000	      2400 ns/op
+BenchmarkNoProcs         	      10	       1.5 ms/op
+BenchmarkBad-8           	    many	      2400 ns/op
+--- BENCH: BenchmarkLog-8
+PASS
+`
+
+func TestParse(t *testing.T) {
+	r, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for _, b := range r.Benchmarks {
+		keys = append(keys, b.Key())
+	}
+	want := []string{
+		"example.com/a BenchmarkParse",
+		"example.com/a BenchmarkParse/small",
+		"example.com/b BenchmarkParse",
+		"example.com/b BenchmarkNoProcs",
+	}
+	if !slices.Equal(keys, want) {
+		t.Errorf("benchmarks %q, want %q", keys, want)
+	}
+
+	tests := []struct {
+		key    string
+		unit   string
+		values []float64
+	}{
+		{key: "example.com/a BenchmarkParse", unit: "ns/op", values: []float64{1200, 1100}},
+		{key: "example.com/a BenchmarkParse", unit: "allocs/op", values: []float64{4, 4}},
+		{key: "example.com/a BenchmarkParse/small", unit: "ns/op", values: []float64{120}},
+		{key: "example.com/b BenchmarkParse", unit: "ns/op", values: []float64{2400}},
+		{key: "example.com/b BenchmarkNoProcs", unit: "ms/op", values: []float64{1.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.unit, func(t *testing.T) {
+			b := r.Get(tt.key)
+			if b == nil {
+				t.Fatal("not found")
+			}
+			if got := b.Values[tt.unit]; !slices.Equal(got, tt.values) {
+				t.Errorf("values %v, want %v", got, tt.values)
+			}
+		})
+	}
+
+	if got := r.Get("example.com/a BenchmarkParse").Units(); !slices.Equal(got, []string{"B/op", "allocs/op", "ns/op"}) {
+		t.Errorf("Units() = %v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+)
+
+// Comparison of a benchmark's values in one unit against a baseline
+type Comparison struct {
+	Key         string
+	Unit        string
+	Old, New    Summary
+	Delta       float64 // Change in the median as a percentage
+	P           float64 // p-value of the Mann-Whitney U test
+	Significant bool    // P is below alpha
+	Regression  bool    // Significant and worse by more than the threshold
+}
+
+// Summary of the values of a benchmark in one unit
+type Summary struct {
+	Median float64
+	Lo, Hi float64 // Confidence interval of the median
+	CI     bool    // Lo & Hi are set
+	N      int
+}
+
+// Summarise returns the summary of values with a 95% confidence interval of the median
+func Summarise(values []float64) Summary {
+	s := Summary{Median: Median(values), N: len(values)}
+	s.Lo, s.Hi, s.CI = MedianCI(values, 0.95)
+	return s
+}
+
+// HigherIsBetter returns true for units which are a rate, e.g. MB/s
+func HigherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+// Compare the results against a baseline. Benchmarks not in both are ignored.
+// A change is significant when its p-value is below alpha, and a regression when it is also
+// worse than the threshold, a percentage.
+func Compare(baseline, results *Results, alpha, threshold float64) []Comparison {
+	var a []Comparison
+	for _, b := range results.Benchmarks {
+		old := baseline.Get(b.Key())
+		if old == nil {
+			continue
+		}
+
+		for _, unit := range b.Units() {
+			ov, nv := old.Values[unit], b.Values[unit]
+			if len(ov) == 0 {
+				continue
+			}
+
+			c := Comparison{
+				Key:  b.Key(),
+				Unit: unit,
+				Old:  Summarise(ov),
+				New:  Summarise(nv),
+				P:    MannWhitneyU(ov, nv),
+			}
+			if c.Old.Median != 0 {
+				c.Delta = 100 * (c.New.Median - c.Old.Median) / c.Old.Median
+			}
+			c.Significant = c.P < alpha
+
+			worse := c.Delta
+			if HigherIsBetter(unit) {
+				worse = -worse
+			}
+			c.Regression = c.Significant && worse > threshold
+
+			a = append(a, c)
+		}
+	}
+	return a
+}
+
+// Regressions returns the comparisons which are regressions
+func Regressions(comparisons []Comparison) []Comparison {
+	var a []Comparison
+	for _, c := range comparisons {
+		if c.Regression {
+			a = append(a, c)
+		}
+	}
+	return a
+}
+
+// WriteTable writes the comparisons like benchstat, the median ± the confidence interval
+// of the baseline & results then the change, or ~ when it is not significant
+func WriteTable(w io.Writer, comparisons []Comparison) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "Benchmark\tUnit\tBaseline\tCurrent\tDelta\tP")
+	for _, c := range comparisons {
+		delta := "~"
+		if c.Significant {
+			delta = fmt.Sprintf("%+.2f%%", c.Delta)
+		}
+		if c.Regression {
+			delta = delta + " regression"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\tp=%.3f n=%d+%d\n",
+			c.Key, c.Unit, c.Old, c.New, delta, c.P, c.Old.N, c.New.N)
+	}
+	return tw.Flush()
+}
+
+// String returns the median ± the larger side of the confidence interval as a percentage, or ∞ if unknown
+func (s Summary) String() string {
+	if s.CI && s.Lo == s.Hi {
+		return fmt.Sprintf("%s ± 0%%", formatValue(s.Median))
+	}
+	if !s.CI || s.Median == 0 {
+		return fmt.Sprintf("%s ± ∞", formatValue(s.Median))
+	}
+	pct := 100 * math.Max(s.Median-s.Lo, s.Hi-s.Median) / s.Median
+	return fmt.Sprintf("%s ± %.0f%%", formatValue(s.Median), pct)
+}
+
+// formatValue formats large values without an exponent, e.g. ns/op, and small ones to 3 significant figures
+func formatValue(v float64) string {
+	if math.Abs(v) >= 100 || v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.3g", v)
+}
@@ -0,0 +1,153 @@
+package bench
+
+import (
+	"math"
+	"sort"
+)
+
+// Median returns the median of the values
+func Median(values []float64) float64 {
+	s := sorted(values)
+	n := len(s)
+	switch {
+	case n == 0:
+		return 0
+	case n%2 == 1:
+		return s[n/2]
+	default:
+		return (s[n/2-1] + s[n/2]) / 2
+	}
+}
+
+// MedianCI returns the confidence interval of the median, e.g. 0.95 for 95%.
+// It is distribution free, using the order statistics of the values, so there must be enough
+// values for the confidence, at least 6 for 95%. ok is false when there are not.
+func MedianCI(values []float64, confidence float64) (lo, hi float64, ok bool) {
+	s := sorted(values)
+	n := len(s)
+
+	// Widen the interval around the median until the probability it contains the median is enough.
+	// The probability the interval s[i]..s[n-1-i] contains it is that between i & n-1-i values are below it.
+	for i := (n - 1) / 2; i >= 0; i-- {
+		p := 0.0
+		for k := i + 1; k <= n-1-i; k++ {
+			p += binomial(n, k)
+		}
+		p = p / math.Pow(2, float64(n))
+		if p >= confidence {
+			return s[i], s[n-1-i], true
+		}
+	}
+	return 0, 0, false
+}
+
+// MannWhitneyU returns the two-sided p-value of the Mann-Whitney U test,
+// the probability both samples come from the same distribution.
+// The exact distribution is used for small samples without ties, otherwise the normal approximation.
+func MannWhitneyU(a, b []float64) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	// Rank both samples together, ties getting the mean of their ranks
+	type value struct {
+		v     float64
+		first bool
+	}
+	var all []value
+	for _, v := range a {
+		all = append(all, value{v: v, first: true})
+	}
+	for _, v := range b {
+		all = append(all, value{v: v})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	r1 := 0.0
+	ties := 0.0 // Sum of t^3-t of each tie
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].first {
+				r1 += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u := r1 - float64(n1*(n1+1))/2
+	u = math.Min(u, float64(n1*n2)-u)
+
+	if ties == 0 && n1*n2 <= 400 {
+		return math.Min(1, 2*exactU(n1, n2, u))
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	sd := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sd == 0 {
+		return 1
+	}
+	z := (math.Abs(u-mean) - 0.5) / sd
+	return math.Min(1, math.Erfc(math.Max(z, 0)/math.Sqrt2))
+}
+
+// exactU returns P(U <= u) for samples of size n1 & n2 without ties
+func exactU(n1, n2 int, u float64) float64 {
+	// counts[i][j][k] is the number of orderings of i & j values where U = k,
+	// built up one sample at a time keeping only the previous i
+	maxU := n1 * n2
+	prev := make([][]float64, n2+1)
+	for j := range prev {
+		prev[j] = make([]float64, maxU+1)
+		prev[j][0] = 1
+	}
+	for i := 1; i <= n1; i++ {
+		cur := make([][]float64, n2+1)
+		cur[0] = make([]float64, maxU+1)
+		cur[0][0] = 1
+		for j := 1; j <= n2; j++ {
+			cur[j] = make([]float64, maxU+1)
+			for k := 0; k <= i*j; k++ {
+				// The largest value is from the first sample so is above all j of the second,
+				// or it is from the second
+				if k >= j {
+					cur[j][k] += prev[j][k-j]
+				}
+				cur[j][k] += cur[j-1][k]
+			}
+		}
+		prev = cur
+	}
+
+	total, below := 0.0, 0.0
+	for k, c := range prev[n2] {
+		total += c
+		if float64(k) <= u {
+			below += c
+		}
+	}
+	return below / total
+}
+
+// binomial returns n choose k
+func binomial(n, k int) float64 {
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+	return r
+}
+
+func sorted(values []float64) []float64 {
+	s := append([]float64{}, values...)
+	sort.Float64s(s)
+	return s
+}
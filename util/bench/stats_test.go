@@ -0,0 +1,87 @@
+package bench
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMedian(t *testing.T) {
+	if m := Median([]float64{3, 1, 2}); m != 2 {
+		t.Errorf("got %v, want 2", m)
+	}
+	if m := Median([]float64{4, 1, 3, 2}); m != 2.5 {
+		t.Errorf("got %v, want 2.5", m)
+	}
+}
+
+func TestMedianCI(t *testing.T) {
+	if _, _, ok := MedianCI([]float64{1, 2, 3, 4, 5}, 0.95); ok {
+		t.Error("expected no 95% interval for 5 values")
+	}
+
+	lo, hi, ok := MedianCI([]float64{6, 1, 5, 2, 4, 3}, 0.95)
+	if !ok || lo != 1 || hi != 6 {
+		t.Errorf("got %v..%v %v, want 1..6", lo, hi, ok)
+	}
+
+	// With 10 values the interval narrows to the 2nd & 9th, 97.9%
+	lo, hi, ok = MedianCI([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0.95)
+	if !ok || lo != 2 || hi != 9 {
+		t.Errorf("got %v..%v %v, want 2..9", lo, hi, ok)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		// Exact, complete separation of 5 & 5 is 2/252
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{"same", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{"interleaved", []float64{1, 3, 5, 7}, []float64{2, 4, 6, 8}, 0.686},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MannWhitneyU(tt.a, tt.b); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("got %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline, err := Parse(strings.NewReader(`pkg: example.com/m
+BenchmarkA-8 100 100 ns/op 10 MB/s
+BenchmarkA-8 100 101 ns/op 10 MB/s
+BenchmarkA-8 100 102 ns/op 10 MB/s
+BenchmarkA-8 100 100 ns/op 10 MB/s
+BenchmarkA-8 100 101 ns/op 10 MB/s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Parse(strings.NewReader(`pkg: example.com/m
+BenchmarkA-4 100 120 ns/op 8 MB/s
+BenchmarkA-4 100 121 ns/op 8 MB/s
+BenchmarkA-4 100 122 ns/op 8 MB/s
+BenchmarkA-4 100 120 ns/op 8 MB/s
+BenchmarkA-4 100 121 ns/op 8 MB/s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Compare(baseline, results, 0.05, 10)
+	if len(c) != 2 {
+		t.Fatalf("got %d comparisons, want 2", len(c))
+	}
+	for _, c := range c {
+		if !c.Regression {
+			t.Errorf("%s %s %+.1f%% p=%.3f expected a regression", c.Key, c.Unit, c.Delta, c.P)
+		}
+	}
+}
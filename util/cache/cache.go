@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"hash"
+)
+
+// Cache stores build outputs by a content addressed key
+type Cache interface {
+	// Get restores the entry for key to dst, returning false if there is no such entry
+	Get(key, dst string) (bool, error)
+	// Put stores src under key
+	Put(key, src string) error
+}
+
+// Key builds a cache key from the inputs of a build
+type Key struct {
+	h hash.Hash
+}
+
+func NewKey() *Key {
+	return &Key{h: sha256.New()}
+}
+
+// Add a named value to the key
+func (k *Key) Add(name, value string) *Key {
+	_, _ = fmt.Fprintf(k.h, "%s=%q\n", name, value)
+	return k
+}
+
+// AddFile adds the name and content of a file to the key
+func (k *Key) AddFile(path string) error {
+	k.Add("file", path)
+	return util.CopyToWriter(path, k.h)
+}
+
+// String returns the key
+func (k *Key) String() string {
+	return hex.EncodeToString(k.h.Sum(nil))
+}
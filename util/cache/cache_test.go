@@ -0,0 +1,66 @@
+package cache
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	key := func(f func(k *Key)) string {
+		k := NewKey()
+		f(k)
+		return k.String()
+	}
+
+	base := key(func(k *Key) {
+		k.Add("tool", "build").Add("GOOS", "linux")
+	})
+
+	if got := key(func(k *Key) { k.Add("tool", "build").Add("GOOS", "linux") }); got != base {
+		t.Errorf("same inputs gave %s, want %s", got, base)
+	}
+
+	if len(base) != 64 {
+		t.Errorf("key %q is not a hex sha256", base)
+	}
+
+	for name, f := range map[string]func(k *Key){
+		"value":   func(k *Key) { k.Add("tool", "build").Add("GOOS", "darwin") },
+		"order":   func(k *Key) { k.Add("GOOS", "linux").Add("tool", "build") },
+		"missing": func(k *Key) { k.Add("tool", "build") },
+		// Values are quoted so they cannot run into the next entry
+		"boundary": func(k *Key) { k.Add("tool", "build\"\nGOOS=\"linux") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := key(f); got == base {
+				t.Errorf("key did not change")
+			}
+		})
+	}
+}
+
+func TestKey_AddFile(t *testing.T) {
+	a := writeFile(t, "main.go", "package main")
+	b := writeFile(t, "main.go", "package main")
+	c := writeFile(t, "main.go", "package other")
+
+	key := func(path string) string {
+		k := NewKey()
+		if err := k.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+		return k.String()
+	}
+
+	// The path is part of the key as well as the content
+	if key(a) == key(b) {
+		t.Errorf("different paths gave the same key")
+	}
+	if key(a) == key(c) {
+		t.Errorf("different content gave the same key")
+	}
+	if key(a) != key(a) {
+		t.Errorf("same file gave different keys")
+	}
+
+	if err := NewKey().AddFile(a + ".missing"); err == nil {
+		t.Errorf("AddFile() on a missing file did not fail")
+	}
+}
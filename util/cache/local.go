@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"github.com/peter-mount/go-build/util"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Local is a Cache held in a local directory
+type Local struct {
+	Dir string
+}
+
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+func (c *Local) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key)
+}
+
+func (c *Local) Get(key, dst string) (bool, error) {
+	src := c.path(key)
+	info, err := os.Stat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+
+	// Remove dst first so we do not write through an existing hard link
+	_ = os.Remove(dst)
+	if err := util.CopyFile(src, dst, info); err != nil {
+		return false, err
+	}
+
+	// Mark it as new so make does not consider it out of date
+	now := time.Now()
+	_ = os.Chtimes(dst, now, now)
+	return true, nil
+}
+
+func (c *Local) Put(key, src string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	dst := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file then rename so concurrent builds never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_ = tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	err = util.CopyFile(src, tmp.Name(), info)
+	if err == nil {
+		// CreateTemp creates the file 0600 so restore the original mode
+		err = os.Chmod(tmp.Name(), info.Mode())
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	return err
+}
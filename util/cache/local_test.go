@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal_PutGet(t *testing.T) {
+	dir := t.TempDir()
+	c := NewLocal(dir)
+
+	dst := filepath.Join(t.TempDir(), "bin", "tool")
+	if hit, err := c.Get("abcd", dst); err != nil || hit {
+		t.Fatalf("Get() on empty cache = %v, %v", hit, err)
+	}
+
+	if err := c.Put("abcd", writeFile(t, "tool", "binary")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries are stored under the first two characters of the key
+	info, err := os.Stat(filepath.Join(dir, "ab", "abcd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("entry mode %v, want 0755", info.Mode().Perm())
+	}
+
+	if hit, err := c.Get("abcd", dst); err != nil || !hit {
+		t.Fatalf("Get() = %v, %v", hit, err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "binary" {
+		t.Errorf("restored %q", b)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Join(dir, "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache contains %d files, want 1", len(entries))
+	}
+}
+
+func TestLocal_GetReplacesExisting(t *testing.T) {
+	c := NewLocal(t.TempDir())
+	if err := c.Put("abcd", writeFile(t, "tool", "new")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := writeFile(t, "tool", "old")
+	if hit, err := c.Get("abcd", dst); err != nil || !hit {
+		t.Fatalf("Get() = %v, %v", hit, err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "new" {
+		t.Errorf("restored %q", b)
+	}
+}
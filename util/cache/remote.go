@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Remote is a Cache held on an HTTP server.
+//
+// Entries are read with GET and written with PUT to "{url}/ac/{key}".
+// The key is a hash of the inputs, not of the content, so this is the action cache of a
+// Bazel remote cache rather than its content addressed store, which checks content against its key.
+// Any server accepting PUT works, e.g. nginx with WebDAV, or bazel-remote when run with
+// --disable_http_ac_validation as entries are not Bazel ActionResults.
+// Basic auth credentials can be included in the url.
+type Remote struct {
+	URL    string // Base url of the cache
+	Write  bool   // true to upload entries, false for read only
+	Client *http.Client
+}
+
+func NewRemote(url string, write bool, timeout time.Duration) *Remote {
+	return &Remote{
+		URL:    strings.TrimSuffix(url, "/"),
+		Write:  write,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Remote) url(key string) string {
+	return c.URL + "/ac/" + key
+}
+
+func (c *Remote) Get(key, dst string) (bool, error) {
+	resp, err := c.Client.Get(c.url(key))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("GET %s: %s", c.url(key), resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+
+	// Write to a temporary file so a failed download never leaves a partial binary
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// Entries are always executables
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	return err == nil, err
+}
+
+func (c *Remote) Put(key, src string) error {
+	if !c.Write {
+		return nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.url(key), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PUT %s: %s", c.url(key), resp.Status)
+	}
+	return nil
+}
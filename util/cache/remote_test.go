@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer is an in-memory remote cache
+type testServer struct {
+	mutex   sync.Mutex
+	entries map[string][]byte
+	delay   time.Duration
+	puts    int
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(s.delay)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/ac/")
+	switch r.Method {
+	case http.MethodGet:
+		b, ok := s.entries[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(b)
+
+	case http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		s.entries[key] = b
+		s.puts++
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestServer(t *testing.T) (*testServer, *httptest.Server) {
+	s := &testServer{entries: make(map[string][]byte)}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fileName, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestRemote_PutGet(t *testing.T) {
+	s, srv := newTestServer(t)
+	c := NewRemote(srv.URL, true, time.Second)
+
+	dst := filepath.Join(t.TempDir(), "bin", "tool")
+	if hit, err := c.Get("abcd", dst); err != nil || hit {
+		t.Fatalf("Get() on empty cache = %v, %v", hit, err)
+	}
+
+	if err := c.Put("abcd", writeFile(t, "tool", "binary")); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.entries["abcd"], []byte("binary")) {
+		t.Fatalf("server has %q", s.entries["abcd"])
+	}
+
+	if hit, err := c.Get("abcd", dst); err != nil || !hit {
+		t.Fatalf("Get() = %v, %v", hit, err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "binary" {
+		t.Errorf("restored %q", b)
+	}
+}
+
+func TestRemote_ReadOnly(t *testing.T) {
+	s, srv := newTestServer(t)
+	c := NewRemote(srv.URL, false, time.Second)
+
+	if err := c.Put("abcd", writeFile(t, "tool", "binary")); err != nil {
+		t.Fatal(err)
+	}
+	if s.puts != 0 {
+		t.Errorf("read only cache made %d puts", s.puts)
+	}
+}
+
+func TestTiered_RemoteHitIsStoredLocally(t *testing.T) {
+	s, srv := newTestServer(t)
+	s.entries["abcd"] = []byte("binary")
+
+	local := NewLocal(t.TempDir())
+	c := Tiered{local, NewRemote(srv.URL, false, time.Second)}
+
+	if hit, err := c.Get("abcd", filepath.Join(t.TempDir(), "tool")); err != nil || !hit {
+		t.Fatalf("Get() = %v, %v", hit, err)
+	}
+
+	srv.Close()
+	if hit, err := local.Get("abcd", filepath.Join(t.TempDir(), "tool")); err != nil || !hit {
+		t.Fatalf("local Get() = %v, %v", hit, err)
+	}
+}
+
+func TestTiered_TimeoutFallsBack(t *testing.T) {
+	s, srv := newTestServer(t)
+	s.entries["abcd"] = []byte("binary")
+	s.delay = 200 * time.Millisecond
+
+	c := Tiered{NewLocal(t.TempDir()), NewRemote(srv.URL, true, 50*time.Millisecond)}
+
+	if hit, err := c.Get("abcd", filepath.Join(t.TempDir(), "tool")); err != nil || hit {
+		t.Fatalf("Get() = %v, %v, expected a miss", hit, err)
+	}
+
+	// Put must not fail the build either
+	if err := c.Put("efgh", writeFile(t, "tool", "binary")); err != nil {
+		t.Fatal(err)
+	}
+}
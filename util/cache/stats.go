@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Stats are the cache hits and misses during a build
+type Stats struct {
+	Hits   int
+	Misses int
+}
+
+// Total returns the number of cache lookups
+func (s Stats) Total() int {
+	return s.Hits + s.Misses
+}
+
+func (s Stats) String() string {
+	if s.Total() == 0 {
+		return "no lookups"
+	}
+	return fmt.Sprintf("%d hits %d misses %d%% hit rate", s.Hits, s.Misses, s.Hits*100/s.Total())
+}
+
+// Record appends a lookup to a stats file.
+// Each build runs in its own process so the file is how they are collected together.
+func Record(fileName string, hit bool, name string) error {
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	_, err = fmt.Fprintf(f, "%s %s\n", result, name)
+	return err
+}
+
+// ReadStats reads a stats file, returning empty Stats if it does not exist
+func ReadStats(fileName string) (Stats, error) {
+	var s Stats
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		switch {
+		case strings.HasPrefix(scanner.Text(), "hit "):
+			s.Hits++
+		case strings.HasPrefix(scanner.Text(), "miss "):
+			s.Misses++
+		}
+	}
+	return s, scanner.Err()
+}
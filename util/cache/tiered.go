@@ -0,0 +1,39 @@
+package cache
+
+import "github.com/peter-mount/go-build/util"
+
+// Tiered is a Cache which checks each of its caches in turn.
+//
+// A hit in a later cache is copied into the earlier ones, so a remote hit is then held locally.
+// Errors from any cache are reported as a warning and treated as a miss,
+// so an unreachable remote cache falls back to building locally.
+type Tiered []Cache
+
+func (t Tiered) Get(key, dst string) (bool, error) {
+	for i, c := range t {
+		hit, err := c.Get(key, dst)
+		if err != nil {
+			util.Label("WARNING", "cache get: %v", err)
+			continue
+		}
+
+		if hit {
+			for _, e := range t[:i] {
+				if err := e.Put(key, dst); err != nil {
+					util.Label("WARNING", "cache put: %v", err)
+				}
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (t Tiered) Put(key, src string) error {
+	for _, c := range t {
+		if err := c.Put(key, src); err != nil {
+			util.Label("WARNING", "cache put: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package changelog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Group of commits within a Release
+type Group struct {
+	Title   string
+	Commits []Commit
+}
+
+// Release holds the commits between the previous v* tag and HEAD
+type Release struct {
+	Version  string    // Version being released
+	Previous string    // Previous tag, blank if there is none
+	Date     time.Time // Date of the release
+	Groups   []*Group  // Groups of commits, in the order they should be rendered
+}
+
+// groups defines the order commit types appear in the release notes.
+// Commit types not listed here, e.g. chore or docs, are not included.
+var groups = []struct {
+	key   string
+	title string
+}{
+	{key: "breaking", title: "Breaking Changes"},
+	{key: "feat", title: "Features"},
+	{key: "fix", title: "Bug Fixes"},
+	{key: "perf", title: "Performance Improvements"},
+	{key: "", title: "Other Changes"},
+}
+
+func git(args ...string) (string, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &buf
+	err := cmd.Run()
+	return strings.TrimSpace(buf.String()), err
+}
+
+// PreviousTag returns the most recent v* tag before HEAD.
+// If HEAD is itself tagged then the tag before that one is returned.
+// Returns "" if there is no previous tag.
+func PreviousTag() string {
+	ref := "HEAD"
+	if _, err := git("describe", "--tags", "--exact-match", "--match=v*", "HEAD"); err == nil {
+		ref = "HEAD^"
+	}
+
+	tag, err := git("describe", "--tags", "--abbrev=0", "--match=v*", ref)
+	if err != nil {
+		return ""
+	}
+	return tag
+}
+
+// New generates a Release for version from the commits since the previous v* tag
+func New(version string) (*Release, error) {
+	r := &Release{
+		Version:  version,
+		Previous: PreviousTag(),
+		Date:     time.Now(),
+	}
+
+	commits, err := Log(r.Previous)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Add(commits...)
+	return r, nil
+}
+
+// Log returns the non-merge commits since from, or all commits if from is blank
+func Log(from string) ([]Commit, error) {
+	args := []string{"log", "--no-merges", "--format=%h%x1f%s%x1f%b%x1e"}
+	if from != "" {
+		args = append(args, from+"..HEAD")
+	}
+
+	s, err := git(args...)
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+
+	var commits []Commit
+	for _, rec := range strings.Split(s, "\x1e") {
+		f := strings.Split(strings.TrimSpace(rec), "\x1f")
+		if len(f) == 3 {
+			commits = append(commits, ParseCommit(f[0], f[1], f[2]))
+		}
+	}
+	return commits, nil
+}
+
+// Add commits to the relevant groups in the Release
+func (r *Release) Add(commits ...Commit) {
+	for _, c := range commits {
+		key := c.Type
+		if c.Breaking {
+			key = "breaking"
+		}
+
+		for i, g := range groups {
+			if g.key == key {
+				r.group(i).Commits = append(r.group(i).Commits, c)
+			}
+		}
+	}
+}
+
+func (r *Release) group(i int) *Group {
+	if r.Groups == nil {
+		r.Groups = make([]*Group, len(groups))
+	}
+	if r.Groups[i] == nil {
+		r.Groups[i] = &Group{Title: groups[i].title}
+	}
+	return r.Groups[i]
+}
+
+// IsEmpty returns true if the Release contains no commits
+func (r *Release) IsEmpty() bool {
+	for _, g := range r.Groups {
+		if g != nil && len(g.Commits) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ForEach calls f for each non-empty Group in order
+func (r *Release) ForEach(f func(*Group)) {
+	for _, g := range r.Groups {
+		if g != nil && len(g.Commits) > 0 {
+			f(g)
+		}
+	}
+}
+
+// Heading returns the markdown heading for this release
+func (r *Release) Heading() string {
+	return fmt.Sprintf("## %s (%s)", r.Version, r.Date.Format(time.DateOnly))
+}
+
+// Markdown returns the release notes in markdown
+func (r *Release) Markdown() string {
+	var a []string
+	a = append(a, r.Heading(), "")
+
+	if r.IsEmpty() {
+		a = append(a, "No notable changes.", "")
+	}
+
+	r.ForEach(func(g *Group) {
+		a = append(a, "### "+g.Title, "")
+		for _, c := range g.Commits {
+			a = append(a, "* "+c.Entry())
+		}
+		a = append(a, "")
+	})
+
+	return strings.Join(a, "\n")
+}
+
+// Debian returns the release as a debian changelog entry.
+// Debian changelogs are plain text so the entries contain no markdown.
+func (r *Release) Debian(name, version, maintainer string) string {
+	var a []string
+	a = append(a, fmt.Sprintf("%s (%s) unstable; urgency=medium", name, version), "")
+
+	if r.IsEmpty() {
+		a = append(a, "  * No notable changes.")
+	}
+
+	r.ForEach(func(g *Group) {
+		for _, c := range g.Commits {
+			a = append(a, "  * "+c.Text())
+		}
+	})
+
+	a = append(a, "", fmt.Sprintf(" -- %s  %s", maintainer, r.Date.Format(time.RFC1123Z)), "")
+
+	return strings.Join(a, "\n")
+}
+
+// Prepend adds the release notes to the top of a CHANGELOG.md style file.
+// If the file starts with a top level heading then the notes are inserted after it.
+// If the file already contains this release then it is left unchanged.
+func (r *Release) Prepend(fileName string) error {
+	b, err := os.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	existing := string(b)
+	if strings.Contains(existing, "## "+r.Version+" (") {
+		return nil
+	}
+
+	header := "# Changelog\n\n"
+	if strings.HasPrefix(existing, "# ") {
+		i := strings.Index(existing, "\n")
+		if i < 0 {
+			i = len(existing) - 1
+		}
+		header = strings.TrimSpace(existing[:i+1]) + "\n\n"
+		existing = strings.TrimLeft(existing[i+1:], "\n")
+	}
+
+	return os.WriteFile(fileName, []byte(header+r.Markdown()+"\n"+existing), 0644)
+}
@@ -0,0 +1,109 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRelease() *Release {
+	r := &Release{
+		Version: "v1.2.0",
+		Date:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	r.Add(
+		Commit{Hash: "a1", Type: "fix", Subject: "wrong arch"},
+		Commit{Hash: "b2", Type: "feat", Scope: "apt", Subject: "changelog"},
+		Commit{Hash: "c3", Type: "chore", Subject: "ignored"},
+		Commit{Hash: "d4", Type: "feat", Subject: "remove flag", Breaking: true},
+	)
+	return r
+}
+
+func TestMarkdown(t *testing.T) {
+	want := `## v1.2.0 (2024-03-01)
+
+### Breaking Changes
+
+* remove flag (d4)
+
+### Features
+
+* **apt:** changelog (b2)
+
+### Bug Fixes
+
+* wrong arch (a1)
+`
+	if got := testRelease().Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	empty := &Release{Version: "v1.0.0", Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if got, want := empty.Markdown(), "## v1.0.0 (2024-03-01)\n\nNo notable changes.\n"; got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
+
+func TestDebian(t *testing.T) {
+	want := `build (1.2.0-1) unstable; urgency=medium
+
+  * remove flag (d4)
+  * apt: changelog (b2)
+  * wrong arch (a1)
+
+ -- Dev <dev@example.com>  Fri, 01 Mar 2024 12:00:00 +0000
+`
+	got := testRelease().Debian("build", "1.2.0-1", "Dev <dev@example.com>")
+	if got != want {
+		t.Errorf("Debian() =\n%s\nwant\n%s", got, want)
+	}
+	for _, md := range []string{"**", "##", "`"} {
+		if strings.Contains(got, md) {
+			t.Errorf("Debian() contains markdown %q", md)
+		}
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	notes := testRelease().Markdown()
+
+	tests := []struct {
+		name     string
+		existing *string
+		want     string
+	}{
+		{name: "missing", want: "# Changelog\n\n" + notes + "\n"},
+		{name: "heading", existing: ptr("# History\n\n## v1.1.0 (2024-01-01)\n"), want: "# History\n\n" + notes + "\n## v1.1.0 (2024-01-01)\n"},
+		{name: "no heading", existing: ptr("## v1.1.0 (2024-01-01)\n"), want: "# Changelog\n\n" + notes + "\n## v1.1.0 (2024-01-01)\n"},
+		{name: "already present", existing: ptr("# Changelog\n\n## v1.2.0 (2024-02-01)\n"), want: "# Changelog\n\n## v1.2.0 (2024-02-01)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fName := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if tt.existing != nil {
+				if err := os.WriteFile(fName, []byte(*tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := testRelease().Prepend(fName); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := os.ReadFile(fName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tt.want {
+				t.Errorf("Prepend() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,65 @@
+package changelog
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Commit is a single commit parsed as a conventional commit
+type Commit struct {
+	Hash     string // Abbreviated commit hash
+	Type     string // Commit type, e.g. feat, fix, perf. Blank if not a conventional commit
+	Scope    string // Optional scope
+	Subject  string // Description from the subject line
+	Breaking bool   // true if this is a breaking change
+}
+
+var conventional = regexp.MustCompile(`^([a-zA-Z]+)(\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// ParseCommit parses a commit subject & body into a Commit
+func ParseCommit(hash, subject, body string) Commit {
+	c := Commit{
+		Hash:    hash,
+		Subject: strings.TrimSpace(subject),
+	}
+
+	if m := conventional.FindStringSubmatch(c.Subject); m != nil {
+		c.Type = strings.ToLower(m[1])
+		c.Scope = m[3]
+		c.Breaking = m[4] == "!"
+		c.Subject = m[5]
+	}
+
+	// A BREAKING CHANGE footer also marks a breaking change
+	for _, l := range strings.Split(body, "\n") {
+		if strings.HasPrefix(l, "BREAKING CHANGE:") || strings.HasPrefix(l, "BREAKING-CHANGE:") {
+			c.Breaking = true
+		}
+	}
+
+	return c
+}
+
+// Entry returns the commit formatted for a changelog entry in markdown
+func (c Commit) Entry() string {
+	if c.Scope != "" {
+		return c.entry("**" + c.Scope + ":** ")
+	}
+	return c.entry("")
+}
+
+// Text returns the commit formatted for a plain text changelog entry, e.g. a debian changelog
+func (c Commit) Text() string {
+	if c.Scope != "" {
+		return c.entry(c.Scope + ": ")
+	}
+	return c.entry("")
+}
+
+func (c Commit) entry(scope string) string {
+	s := scope + c.Subject
+	if c.Hash != "" {
+		s = s + " (" + c.Hash + ")"
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package changelog
+
+import "testing"
+
+func TestParseCommit(t *testing.T) {
+	tests := []struct {
+		subject string
+		body    string
+		want    Commit
+	}{
+		{subject: "feat: add thing", want: Commit{Type: "feat", Subject: "add thing"}},
+		{subject: "fix(apt): wrong arch", want: Commit{Type: "fix", Scope: "apt", Subject: "wrong arch"}},
+		{subject: "feat!: remove flag", want: Commit{Type: "feat", Subject: "remove flag", Breaking: true}},
+		{subject: "perf: faster", body: "Some text\n\nBREAKING CHANGE: api changed", want: Commit{Type: "perf", Subject: "faster", Breaking: true}},
+		{subject: "Update README.md", want: Commit{Subject: "Update README.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			if got := ParseCommit("", tt.subject, tt.body); got != tt.want {
+				t.Errorf("ParseCommit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommit_Entry(t *testing.T) {
+	tests := []struct {
+		commit Commit
+		entry  string
+		text   string
+	}{
+		{commit: Commit{Hash: "a1", Subject: "wrong arch"}, entry: "wrong arch (a1)", text: "wrong arch (a1)"},
+		{commit: Commit{Hash: "b2", Scope: "apt", Subject: "changelog"}, entry: "**apt:** changelog (b2)", text: "apt: changelog (b2)"},
+		{commit: Commit{Scope: "apt", Subject: "changelog"}, entry: "**apt:** changelog", text: "apt: changelog"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := tt.commit.Entry(); got != tt.entry {
+				t.Errorf("Entry() = %q, want %q", got, tt.entry)
+			}
+			if got := tt.commit.Text(); got != tt.text {
+				t.Errorf("Text() = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cobertura struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      string             `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity string           `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity string          `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// WriteCobertura writes the profile in Cobertura XML format.
+// Files within module are made relative to source, the root directory of the module,
+// with a class per file and a package per go package.
+func (p *Profile) WriteCobertura(w io.Writer, module, source string) error {
+	c := cobertura{
+		BranchRate: "0",
+		Complexity: "0",
+		Timestamp:  time.Now().UnixMilli(),
+		Sources:    []string{source},
+	}
+
+	packages := make(map[string]*coberturaPackage)
+	pkgLines := make(map[string][2]int)
+
+	for _, file := range p.FileNames() {
+		name := strings.TrimPrefix(strings.TrimPrefix(file, module), "/")
+		pkgName := path.Dir(file)
+
+		pkg := packages[pkgName]
+		if pkg == nil {
+			pkg = &coberturaPackage{Name: pkgName, BranchRate: "0", Complexity: "0"}
+			packages[pkgName] = pkg
+		}
+
+		class := coberturaClass{
+			Name:       path.Base(file),
+			Filename:   name,
+			BranchRate: "0",
+			Complexity: "0",
+		}
+
+		lines := p.Lines(file)
+		covered := 0
+		for _, l := range sortedLines(lines) {
+			class.Lines = append(class.Lines, coberturaLine{Number: l, Hits: lines[l]})
+			if lines[l] > 0 {
+				covered++
+			}
+		}
+		class.LineRate = rate(covered, len(lines))
+		pkg.Classes = append(pkg.Classes, class)
+
+		pl := pkgLines[pkgName]
+		pkgLines[pkgName] = [2]int{pl[0] + covered, pl[1] + len(lines)}
+		c.LinesCovered += covered
+		c.LinesValid += len(lines)
+	}
+
+	var names []string
+	for n := range packages {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		pkg := packages[n]
+		pkg.LineRate = rate(pkgLines[n][0], pkgLines[n][1])
+		c.Packages = append(c.Packages, *pkg)
+	}
+	c.LineRate = rate(c.LinesCovered, c.LinesValid)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func sortedLines(lines map[int]int) []int {
+	var a []int
+	for l := range lines {
+		a = append(a, l)
+	}
+	sort.Ints(a)
+	return a
+}
+
+func rate(covered, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(covered)/float64(total), 'f', 4, 64)
+}
@@ -0,0 +1,105 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// PackageCoverage is the statement coverage of a package
+type PackageCoverage struct {
+	Name       string
+	Statements int
+	Covered    int
+}
+
+// Percent returns the percentage of statements covered
+func (c PackageCoverage) Percent() float64 {
+	if c.Statements == 0 {
+		return 0
+	}
+	return 100 * float64(c.Covered) / float64(c.Statements)
+}
+
+// Packages returns the coverage of each package, sorted by name
+func (p *Profile) Packages() []PackageCoverage {
+	packages := make(map[string]*PackageCoverage)
+	var names []string
+
+	for _, file := range p.FileNames() {
+		name := path.Dir(file)
+		c := packages[name]
+		if c == nil {
+			c = &PackageCoverage{Name: name}
+			packages[name] = c
+			names = append(names, name)
+		}
+
+		total, covered := p.Statements(file)
+		c.Statements += total
+		c.Covered += covered
+	}
+
+	sort.Strings(names)
+	var a []PackageCoverage
+	for _, n := range names {
+		a = append(a, *packages[n])
+	}
+	return a
+}
+
+// Total returns the coverage of the whole profile
+func (p *Profile) Total() PackageCoverage {
+	t := PackageCoverage{Name: "Total"}
+	for _, file := range p.FileNames() {
+		total, covered := p.Statements(file)
+		t.Statements += total
+		t.Covered += covered
+	}
+	return t
+}
+
+// Thresholds are the minimum percentage of statements which must be covered, 0 for none
+type Thresholds struct {
+	Total    float64            // Minimum total coverage
+	Package  float64            // Minimum coverage of every package
+	Packages map[string]float64 // Minimum coverage of individual packages, overriding Package
+}
+
+// Check returns an error listing the coverage which is below the thresholds
+func (p *Profile) Check(t Thresholds) error {
+	var failures []string
+
+	for _, c := range p.Packages() {
+		want := t.Package
+		if m, exists := t.Packages[c.Name]; exists {
+			want = m
+		}
+		if c.Statements > 0 && c.Percent() < want {
+			failures = append(failures, fmt.Sprintf("%s %.1f%% < %.1f%%", c.Name, c.Percent(), want))
+		}
+	}
+
+	if total := p.Total(); total.Percent() < t.Total {
+		failures = append(failures, fmt.Sprintf("total %.1f%% < %.1f%%", total.Percent(), t.Total))
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("coverage below threshold:\n  %s", strings.Join(failures, "\n  "))
+	}
+	return nil
+}
+
+// WriteTable writes the coverage of each package followed by the total
+func (p *Profile) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "Package\tStatements\tCoverage")
+	for _, c := range append(p.Packages(), p.Total()) {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", c.Name, c.Statements, c.Percent())
+	}
+	return tw.Flush()
+}
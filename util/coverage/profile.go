@@ -0,0 +1,179 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Profile is a coverage profile written by go test -coverprofile
+type Profile struct {
+	Mode   string
+	Files  map[string][]Block // Blocks by file, e.g. github.com/user/module/pkg/file.go
+	blocks map[blockKey]int   // Index of each block in Files
+}
+
+// Block is a block of statements within a file
+type Block struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	Statements          int
+	Count               int
+}
+
+// blockKey identifies a block within the profile
+type blockKey struct {
+	file                string
+	startLine, startCol int
+	endLine, endCol     int
+}
+
+// modes are the coverage modes, in the order a merged profile uses the last of them
+var modes = []string{"set", "count", "atomic"}
+
+// NewProfile returns an empty profile
+func NewProfile(mode string) *Profile {
+	return &Profile{
+		Mode:   mode,
+		Files:  make(map[string][]Block),
+		blocks: make(map[blockKey]int),
+	}
+}
+
+// ReadProfile reads a coverage profile from a file
+func ReadProfile(name string) (*Profile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseProfile(f)
+}
+
+// ParseProfile parses a coverage profile.
+// Blocks which appear more than once, e.g. when go test was run with -coverpkg, are merged.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	p := NewProfile("")
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+
+		case strings.HasPrefix(line, "mode:"):
+			p.Mode = strings.TrimSpace(strings.TrimPrefix(line, "mode:"))
+
+		default:
+			// name.go:line.column,line.column numberOfStatements count
+			i := strings.LastIndex(line, ":")
+			if i < 0 {
+				return nil, fmt.Errorf("line %d: invalid coverage %q", n, line)
+			}
+
+			b := Block{}
+			_, err := fmt.Sscanf(line[i+1:], "%d.%d,%d.%d %d %d",
+				&b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.Statements, &b.Count)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid coverage %q: %w", n, line, err)
+			}
+
+			p.add(line[:i], b)
+		}
+	}
+
+	return p, scanner.Err()
+}
+
+// add a block to the profile, merging it with an existing block
+func (p *Profile) add(file string, b Block) {
+	k := blockKey{file: file, startLine: b.StartLine, startCol: b.StartCol, endLine: b.EndLine, endCol: b.EndCol}
+	if i, exists := p.blocks[k]; exists {
+		e := &p.Files[file][i]
+		if p.Mode == "set" {
+			e.Count = max(e.Count, b.Count)
+		} else {
+			e.Count += b.Count
+		}
+		return
+	}
+
+	p.blocks[k] = len(p.Files[file])
+	p.Files[file] = append(p.Files[file], b)
+}
+
+// Merge another profile into this one.
+// Profiles with different modes, e.g. set from a plain run and atomic from a run with -race, are merged
+// in the mode which counts the most, as a set block's count of 0 or 1 is also a valid count.
+func (p *Profile) Merge(o *Profile) error {
+	for _, m := range []string{p.Mode, o.Mode} {
+		if m != "" && !slices.Contains(modes, m) {
+			return fmt.Errorf("unsupported coverage mode %q", m)
+		}
+	}
+	if slices.Index(modes, o.Mode) > slices.Index(modes, p.Mode) {
+		p.Mode = o.Mode
+	}
+
+	for _, file := range o.FileNames() {
+		for _, b := range o.Files[file] {
+			p.add(file, b)
+		}
+	}
+	return nil
+}
+
+// Write the profile in the format go test -coverprofile writes
+func (p *Profile) Write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "mode: %s\n", p.Mode); err != nil {
+		return err
+	}
+	for _, file := range p.FileNames() {
+		for _, b := range p.Files[file] {
+			_, err := fmt.Fprintf(w, "%s:%d.%d,%d.%d %d %d\n",
+				file, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.Statements, b.Count)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FileNames returns the names of the files in the profile, sorted
+func (p *Profile) FileNames() []string {
+	var names []string
+	for n := range p.Files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lines returns the number of times each line in a file was run
+func (p *Profile) Lines(file string) map[int]int {
+	lines := make(map[int]int)
+	for _, b := range p.Files[file] {
+		for l := b.StartLine; l <= b.EndLine; l++ {
+			if c, exists := lines[l]; !exists || b.Count > c {
+				lines[l] = b.Count
+			}
+		}
+	}
+	return lines
+}
+
+// Statements returns the number of statements and how many of those were covered in a file
+func (p *Profile) Statements(file string) (total, covered int) {
+	for _, b := range p.Files[file] {
+		total += b.Statements
+		if b.Count > 0 {
+			covered += b.Statements
+		}
+	}
+	return
+}
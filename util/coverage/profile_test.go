@@ -0,0 +1,144 @@
+package coverage
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+const testProfile = `mode: set
+example.com/m/a/a.go:3.10,5.2 2 1
+example.com/m/a/a.go:5.2,7.3 1 0
+example.com/m/b.go:1.1,2.2 1 0
+`
+
+func TestParseProfile(t *testing.T) {
+	p, err := ParseProfile(strings.NewReader(testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Mode != "set" {
+		t.Errorf("got mode %q, want set", p.Mode)
+	}
+
+	total, covered := p.Statements("example.com/m/a/a.go")
+	if total != 3 || covered != 2 {
+		t.Errorf("got %d/%d statements, want 2/3", covered, total)
+	}
+
+	// Line 5 is in both blocks so counts as run
+	lines := p.Lines("example.com/m/a/a.go")
+	for l, want := range map[int]int{3: 1, 5: 1, 6: 0, 7: 0} {
+		if lines[l] != want {
+			t.Errorf("line %d got %d, want %d", l, lines[l], want)
+		}
+	}
+}
+
+func TestWriteCobertura(t *testing.T) {
+	p, err := ParseProfile(strings.NewReader(testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := p.WriteCobertura(&sb, "example.com/m", "/src"); err != nil {
+		t.Fatal(err)
+	}
+	s := sb.String()
+
+	for _, want := range []string{
+		`<source>/src</source>`,
+		`<package name="example.com/m/a" line-rate="0.6000"`,
+		`<class name="a.go" filename="a/a.go" line-rate="0.6000"`,
+		`<class name="b.go" filename="b.go" line-rate="0.0000"`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %s in\n%s", want, s)
+		}
+	}
+}
+
+func TestProfile_Merge(t *testing.T) {
+	p, err := ParseProfile(strings.NewReader(testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same block from another test binary, e.g. with -coverpkg
+	o, err := ParseProfile(strings.NewReader("mode: set\nexample.com/m/b.go:1.1,2.2 1 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Merge(o); err != nil {
+		t.Fatal(err)
+	}
+
+	if total, covered := p.Statements("example.com/m/b.go"); total != 1 || covered != 1 {
+		t.Errorf("got %d/%d statements, want 1/1", covered, total)
+	}
+
+	if err := p.Merge(&Profile{Mode: "bogus"}); err == nil {
+		t.Error("expected error merging an unsupported mode")
+	}
+}
+
+func TestProfile_MergeModes(t *testing.T) {
+	// A plain run in set mode and a run with -race in atomic mode
+	plain := "mode: set\nexample.com/m/a.go:1.1,2.2 1 1\nexample.com/m/a.go:3.1,4.2 1 0\n"
+	race := "mode: atomic\nexample.com/m/a.go:1.1,2.2 1 3\nexample.com/m/a.go:5.1,6.2 1 2\n"
+	want := map[int]int{1: 4, 3: 0, 5: 2} // Count by start line
+
+	for _, order := range [][]string{{plain, race}, {race, plain}} {
+		p := NewProfile("")
+		for _, s := range order {
+			o, err := ParseProfile(strings.NewReader(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Merge(o); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if p.Mode != "atomic" {
+			t.Errorf("Mode = %q, want atomic", p.Mode)
+		}
+		got := make(map[int]int)
+		for _, b := range p.Files["example.com/m/a.go"] {
+			got[b.StartLine] = b.Count
+		}
+		if !maps.Equal(got, want) {
+			t.Errorf("counts = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestProfile_Check(t *testing.T) {
+	p, err := ParseProfile(strings.NewReader(testProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 of 4 statements covered, example.com/m/a 2 of 3
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		wantErr    bool
+	}{
+		{"none", Thresholds{}, false},
+		{"total", Thresholds{Total: 50}, false},
+		{"total below", Thresholds{Total: 60}, true},
+		{"package below", Thresholds{Package: 50}, true},
+		{"package override", Thresholds{Package: 50, Packages: map[string]float64{"example.com/m": 0}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.Check(tt.thresholds); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
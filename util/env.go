@@ -0,0 +1,23 @@
+package util
+
+import (
+	"os"
+	"regexp"
+)
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv replaces ${NAME} with the environment variable NAME.
+// Unlike os.ExpandEnv, $NAME and any other use of $ are left as is, so values like passwords may contain $.
+func ExpandEnv(s string) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(envRef.FindStringSubmatch(ref)[1])
+	})
+}
+
+// ExpandEnvAll calls ExpandEnv on each string
+func ExpandEnvAll(a ...*string) {
+	for _, s := range a {
+		*s = ExpandEnv(*s)
+	}
+}
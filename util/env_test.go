@@ -0,0 +1,26 @@
+package util
+
+import "testing"
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GO_BUILD_USER", "user")
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "${GO_BUILD_USER}", want: "user"},
+		{in: "https://${GO_BUILD_USER}@example.com", want: "https://user@example.com"},
+		{in: "${GO_BUILD_UNSET}", want: ""},
+		{in: "pa$$word", want: "pa$$word"},
+		{in: "$GO_BUILD_USER", want: "$GO_BUILD_USER"},
+		{in: "${not valid}", want: "${not valid}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ExpandEnv(tt.in); got != tt.want {
+				t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/peter-mount/go-build/util/makefile"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Executor runs the rules in a makefile.Graph without make
+type Executor struct {
+	Graph     *makefile.Graph
+	Jobs      int       // Maximum number of rules run concurrently, defaults to the number of CPUs
+	KeepGoing bool      // true to build as much as possible after a failure, false to stop at the first one
+	Out       io.Writer // Where command output is written, defaults to os.Stdout
+	mutex     sync.Mutex
+	done      map[string]bool // Targets completed by earlier goals
+}
+
+// Summary of a Run
+type Summary struct {
+	Built    int           // Rules whose commands were run
+	UpToDate int           // Rules which were already up to date
+	Failed   int           // Rules which failed
+	Skipped  int           // Rules not run due to a failure
+	Failures []string      // Description of each failure
+	Duration time.Duration // Duration of the run
+}
+
+func (s *Summary) String() string {
+	return fmt.Sprintf("%d built %d up to date %d failed %d skipped in %s",
+		s.Built, s.UpToDate, s.Failed, s.Skipped, s.Duration.Round(time.Millisecond))
+}
+
+// OK returns true if there were no failures
+func (s *Summary) OK() bool {
+	return s.Failed == 0
+}
+
+type state struct {
+	node       *makefile.Node
+	deps       []string // Expanded dependencies
+	pending    int      // Number of dependencies yet to complete
+	dependents []*state // Rules waiting on this one
+	failed     bool     // true if this rule or a dependency failed
+}
+
+type result struct {
+	state *state
+	ran   bool
+	err   error
+}
+
+func New(g *makefile.Graph) *Executor {
+	return &Executor{
+		Graph: g,
+		Jobs:  runtime.NumCPU(),
+		Out:   os.Stdout,
+	}
+}
+
+// plan returns the rules required to build the targets
+func (e *Executor) plan(targets []string) (map[string]*state, error) {
+	states := make(map[string]*state)
+	visiting := make(map[string]bool)
+
+	var visit func(target, parent string) (*state, error)
+	visit = func(target, parent string) (*state, error) {
+		if s, exists := states[target]; exists {
+			return s, nil
+		}
+		if e.done[target] {
+			return nil, nil
+		}
+		if visiting[target] {
+			return nil, fmt.Errorf("circular dependency %s <- %s", target, parent)
+		}
+
+		n := e.Graph.Get(target)
+		if n == nil {
+			// No rule so it must be a file which already exists
+			if _, err := os.Stat(target); err != nil {
+				if parent == "" {
+					return nil, fmt.Errorf("no rule to make target %q", target)
+				}
+				return nil, fmt.Errorf("no rule to make target %q, needed by %q", target, parent)
+			}
+			return nil, nil
+		}
+
+		visiting[target] = true
+		s := &state{node: n, deps: e.Graph.Dependencies(n)}
+		for _, d := range s.deps {
+			ds, err := visit(d, target)
+			if err != nil {
+				return nil, err
+			}
+			if ds != nil {
+				s.pending++
+				ds.dependents = append(ds.dependents, s)
+			}
+		}
+		delete(visiting, target)
+
+		states[target] = s
+		return s, nil
+	}
+
+	for _, t := range targets {
+		if _, err := visit(t, ""); err != nil {
+			return nil, err
+		}
+	}
+	return states, nil
+}
+
+// Run builds the targets.
+//
+// Like make, each target is built in turn so "clean all" cleans before building.
+// Rules are only run once even if more than one target depends on them.
+func (e *Executor) Run(targets ...string) (*Summary, error) {
+	start := time.Now()
+	summary := &Summary{}
+	e.done = make(map[string]bool)
+
+	for _, t := range targets {
+		if err := e.run(t, summary); err != nil {
+			return nil, err
+		}
+		if !summary.OK() && !e.KeepGoing {
+			break
+		}
+	}
+
+	summary.Duration = time.Since(start)
+	return summary, nil
+}
+
+func (e *Executor) run(target string, summary *Summary) error {
+	states, err := e.plan([]string{target})
+	if err != nil {
+		return err
+	}
+
+	jobs := e.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	env := append(os.Environ(), e.Graph.Env()...)
+	rules, finished := 0, 0
+
+	// Start with the rules which have no dependencies, sorted so runs are repeatable
+	var ready []*state
+	for _, s := range states {
+		if s.pending == 0 {
+			ready = append(ready, s)
+		}
+		if len(s.node.Commands) > 0 {
+			rules++
+		}
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		return ready[i].node.Target < ready[j].node.Target
+	})
+
+	results := make(chan result)
+	running, stop := 0, false
+
+	for {
+		for len(ready) > 0 && running < jobs && !stop {
+			s := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				ran, err := e.build(s, env)
+				results <- result{state: s, ran: ran, err: err}
+			}()
+		}
+
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		e.done[r.state.node.Target] = r.err == nil
+		if len(r.state.node.Commands) > 0 {
+			finished++
+		}
+
+		switch {
+		case r.err == nil && len(r.state.node.Commands) == 0:
+			// Rules without commands only group others together
+		case r.err != nil:
+			r.state.failed = true
+			summary.Failed++
+			summary.Failures = append(summary.Failures, r.state.node.Target+": "+r.err.Error())
+			stop = !e.KeepGoing
+		case r.ran:
+			summary.Built++
+		default:
+			summary.UpToDate++
+		}
+
+		for _, d := range r.state.dependents {
+			release(d, r.state.failed, &ready)
+		}
+	}
+
+	// Anything not run was skipped after a failure
+	summary.Skipped += rules - finished
+	return nil
+}
+
+// release notes a dependency of s has completed, adding s to ready once all of them have.
+// If a dependency failed then s is never run and its own dependents are released as failed.
+func release(s *state, failed bool, ready *[]*state) {
+	s.failed = s.failed || failed
+	s.pending--
+	if s.pending > 0 {
+		return
+	}
+
+	if !s.failed {
+		*ready = append(*ready, s)
+		return
+	}
+
+	for _, d := range s.dependents {
+		release(d, true, ready)
+	}
+}
+
+// outOfDate returns true if the rule needs to run
+func (e *Executor) outOfDate(s *state) bool {
+	if e.Graph.IsPhony(s.node.Target) {
+		return true
+	}
+
+	info, err := os.Stat(s.node.Target)
+	if err != nil {
+		return true
+	}
+
+	for _, d := range s.deps {
+		if e.Graph.IsPhony(d) {
+			return true
+		}
+		di, err := os.Stat(d)
+		if err != nil || di.ModTime().After(info.ModTime()) {
+			return true
+		}
+	}
+	return false
+}
+
+// build runs the commands of a rule if it is out of date.
+// Returns true if commands were run.
+func (e *Executor) build(s *state, env []string) (bool, error) {
+	if len(s.node.Commands) == 0 || !e.outOfDate(s) {
+		return false, nil
+	}
+
+	// Buffer the output so parallel rules do not interleave
+	var buf bytes.Buffer
+	var err error
+	for _, l := range s.node.Commands {
+		c := parseCommand(l, e.Graph.Expand)
+		if c.line == "" {
+			continue
+		}
+
+		if cerr := c.run(env, &buf); cerr != nil && !c.ignoreError {
+			err = cerr
+			break
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	_, _ = e.Out.Write(buf.Bytes())
+	if err != nil && !strings.HasSuffix(buf.String(), "\n") && buf.Len() > 0 {
+		_, _ = fmt.Fprintln(e.Out)
+	}
+
+	return true, err
+}
@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"bytes"
+	"github.com/peter-mount/go-build/util/makefile"
+	"strings"
+	"testing"
+)
+
+// missing is a command which does not exist so fails without a shell
+const missing = "go-build-missing-command"
+
+func newExecutor(b makefile.Builder, keepGoing bool) (*Executor, *bytes.Buffer) {
+	var out bytes.Buffer
+	e := New(makefile.NewGraph(b))
+	e.Jobs = 1
+	e.KeepGoing = keepGoing
+	e.Out = &out
+	return e, &out
+}
+
+func TestExecutor_Order(t *testing.T) {
+	b := makefile.New()
+	b.Phony("all", "a", "b", "c")
+	b.Rule("all", "a", "b")
+	b.Rule("a", "c").Line("@echo a")
+	b.Rule("b", "c").Line("@echo b")
+	b.Rule("c").Line("@echo c")
+
+	e, out := newExecutor(b, false)
+	summary, err := e.Run("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// c only runs once and before the rules which depend on it
+	if got := out.String(); got != "c\na\nb\n" {
+		t.Errorf("output %q", got)
+	}
+	if summary.Built != 3 || summary.Failed != 0 || summary.Skipped != 0 || !summary.OK() {
+		t.Errorf("summary %s", summary)
+	}
+}
+
+func TestExecutor_Goals(t *testing.T) {
+	b := makefile.New()
+	b.Phony("clean", "all", "init")
+	b.Rule("init").Line("@echo init")
+	b.Rule("clean").Line("@echo clean")
+	b.Rule("all", "init").Line("@echo all")
+
+	e, out := newExecutor(b, false)
+	if _, err := e.Run("clean", "all", "init"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Goals run in order and init is not run again
+	if got := out.String(); got != "clean\ninit\nall\n" {
+		t.Errorf("output %q", got)
+	}
+}
+
+func TestExecutor_Failure(t *testing.T) {
+	build := func() makefile.Builder {
+		b := makefile.New()
+		b.Phony("all", "a", "afail", "b")
+		b.Rule("all", "a", "b")
+		b.Rule("a", "afail").Line("@echo a")
+		b.Rule("afail").Line("@" + missing)
+		b.Rule("b").Line("@echo b")
+		return b
+	}
+
+	tests := []struct {
+		name      string
+		keepGoing bool
+		built     int
+		skipped   int
+		out       string
+	}{
+		// afail runs first so nothing else is run
+		{name: "stop", keepGoing: false, built: 0, skipped: 2, out: ""},
+		// b does not depend on afail so is still built, a is not
+		{name: "keep going", keepGoing: true, built: 1, skipped: 1, out: "b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, out := newExecutor(build(), tt.keepGoing)
+			summary, err := e.Run("all")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if summary.OK() || summary.Failed != 1 || summary.Built != tt.built || summary.Skipped != tt.skipped {
+				t.Errorf("summary %s", summary)
+			}
+			if len(summary.Failures) != 1 || !strings.HasPrefix(summary.Failures[0], "afail: ") {
+				t.Errorf("failures %q", summary.Failures)
+			}
+			if got := out.String(); got != tt.out {
+				t.Errorf("output %q, want %q", got, tt.out)
+			}
+		})
+	}
+}
+
+func TestExecutor_IgnoreError(t *testing.T) {
+	b := makefile.New()
+	b.Phony("all")
+	b.Rule("all").
+		Line("-@" + missing).
+		Line("@echo ok")
+
+	e, out := newExecutor(b, false)
+	summary, err := e.Run("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.OK() || summary.Built != 1 {
+		t.Errorf("summary %s", summary)
+	}
+	if got := out.String(); got != "ok\n" {
+		t.Errorf("output %q", got)
+	}
+}
+
+func TestExecutor_Plan(t *testing.T) {
+	b := makefile.New()
+	b.Phony("a", "b", "c")
+	b.Rule("a", "b").Line("@echo a")
+	b.Rule("b", "a").Line("@echo b")
+	b.Rule("c", "go-build-missing-file").Line("@echo c")
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "a", want: "circular dependency"},
+		{target: "c", want: `no rule to make target "go-build-missing-file", needed by "c"`},
+		{target: "d", want: `no rule to make target "d"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			e, _ := newExecutor(b, false)
+			_, err := e.Run(tt.target)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Run(%q) = %v, want %q", tt.target, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"github.com/peter-mount/go-build/util/makefile"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// command is a single recipe line ready to run
+type command struct {
+	line        string // Expanded command line
+	ignoreError bool   // "-" prefix, ignore failures
+	shell       bool   // true if the line must be run by the shell
+}
+
+// parseCommand parses a recipe line, expanding its variables.
+// Whether the shell is needed is decided before expansion, so the values of variables,
+// e.g. a Windows path in $(BUILD), do not cause the line to be run by the shell.
+func parseCommand(line string, expand func(string) string) command {
+	l, ignoreError := makefile.ParseCommand(line)
+	return command{
+		line:        expand(l),
+		ignoreError: ignoreError,
+		shell:       needsShell(l, runtime.GOOS == "windows"),
+	}
+}
+
+// needsShell returns true if an unexpanded line uses shell features we do not handle natively.
+// On Windows a backslash is a path separator, elsewhere it is an escape so needs the shell.
+func needsShell(line string, windows bool) bool {
+	chars := "|&<>`*?$(){}\n"
+	if !windows {
+		chars = chars + `\`
+	}
+	return strings.ContainsAny(makefile.StripReferences(line), chars)
+}
+
+// tokenize splits a line into words, honouring single & double quotes.
+// A ";" outside of quotes is returned as its own token.
+func tokenize(line string) ([]string, error) {
+	var (
+		tokens []string
+		sb     strings.Builder
+		quote  rune
+		inWord bool
+	)
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, sb.String())
+			sb.Reset()
+			inWord = false
+		}
+	}
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				sb.WriteRune(r)
+			}
+
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+
+		case r == ';':
+			flush()
+			tokens = append(tokens, ";")
+
+		case r == ' ' || r == '\t':
+			flush()
+
+		default:
+			sb.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	flush()
+	return tokens, nil
+}
+
+// run executes a command line.
+//
+// Simple lines are run directly with echo, mkdir -p and rm -rf handled natively,
+// so the generated rules work where there is no shell, e.g. Windows.
+// Anything else is passed to the shell.
+func (c command) run(env []string, out io.Writer) error {
+	if c.shell {
+		return c.exec(env, out, shell(c.line)...)
+	}
+
+	tokens, err := tokenize(c.line)
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	for _, t := range append(tokens, ";") {
+		if t != ";" {
+			args = append(args, t)
+			continue
+		}
+
+		if len(args) > 0 {
+			if err := c.simple(env, out, args); err != nil {
+				return err
+			}
+		}
+		args = nil
+	}
+	return nil
+}
+
+func shell(line string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", line}
+	}
+	return []string{"sh", "-c", line}
+}
+
+func (c command) simple(env []string, out io.Writer, args []string) error {
+	switch args[0] {
+	case "echo":
+		_, err := fmt.Fprintln(out, strings.Join(args[1:], " "))
+		return err
+
+	case "mkdir":
+		if len(args) > 1 && args[1] == "-p" {
+			for _, d := range args[2:] {
+				if err := os.MkdirAll(d, 0755); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case "rm":
+		if len(args) > 1 && strings.HasPrefix(args[1], "-") && strings.Contains(args[1], "f") {
+			for _, d := range args[2:] {
+				if err := os.RemoveAll(d); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return c.exec(env, out, args...)
+}
+
+func (c command) exec(env []string, out io.Writer, args ...string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		err = fmt.Errorf("%s: exit status %d", args[0], exit.ExitCode())
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "echo a b", want: []string{"echo", "a", "b"}},
+		{line: "  echo\ta  ", want: []string{"echo", "a"}},
+		{line: `echo "a b" 'c d'`, want: []string{"echo", "a b", "c d"}},
+		{line: `echo "a;b"`, want: []string{"echo", "a;b"}},
+		{line: `echo "BUILD x";go build`, want: []string{"echo", "BUILD x", ";", "go", "build"}},
+		{line: `echo ""`, want: []string{"echo", ""}},
+		{line: `C:\builds\build.exe -go build`, want: []string{`C:\builds\build.exe`, "-go", "build"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := tokenize(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenize_Unterminated(t *testing.T) {
+	if _, err := tokenize(`echo "a`); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestNeedsShell(t *testing.T) {
+	tests := []struct {
+		line    string
+		windows bool
+		want    bool
+	}{
+		{line: "mkdir -p builds", want: false},
+		{line: `echo "BUILD x";$(BUILD) -go build`, want: false},
+		{line: `echo "BUILD x";$(BUILD) -go build`, windows: true, want: false},
+		{line: "$(or $(A),b) x", want: false},
+		{line: "cd dist && sha256sum *", want: true},
+		{line: "a | b", want: true},
+		{line: "a > b", want: true},
+		{line: "rm -f $$HOME/x", want: true},
+		{line: "echo `date`", want: true},
+		{line: `dir C:\builds`, want: true},
+		{line: `dir C:\builds`, windows: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := needsShell(tt.line, tt.windows); got != tt.want {
+				t.Errorf("needsShell(%q, %v) = %v, want %v", tt.line, tt.windows, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	expand := func(string) string { return `C:\builds\build.exe -go build` }
+
+	c := parseCommand("-@$(BUILD) -go build", expand)
+	if c.line != `C:\builds\build.exe -go build` || !c.ignoreError || c.shell {
+		t.Errorf("parseCommand() = %+v", c)
+	}
+}
@@ -0,0 +1,128 @@
+package gitlab
+
+import (
+	"gopkg.in/yaml.v2"
+)
+
+// Pipeline is a GitLab CI pipeline, the content of .gitlab-ci.yml
+type Pipeline struct {
+	Stages    []string          `yaml:"stages"`
+	Default   *Default          `yaml:"default,omitempty"`
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Jobs      yaml.MapSlice     `yaml:"-"` // Jobs in the order they were added
+}
+
+type Default struct {
+	Image string `yaml:"image,omitempty"`
+	Cache *Cache `yaml:"cache,omitempty"`
+}
+
+type Cache struct {
+	Key   string   `yaml:"key"`
+	Paths []string `yaml:"paths"`
+}
+
+type Job struct {
+	Stage     string            `yaml:"stage"`
+	Needs     []any             `yaml:"needs,omitempty"`
+	Rules     []Rule            `yaml:"rules,omitempty"`
+	Parallel  *Parallel         `yaml:"parallel,omitempty"`
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Script    []string          `yaml:"script"`
+	Artifacts *Artifacts        `yaml:"artifacts,omitempty"`
+}
+
+type Rule struct {
+	If string `yaml:"if"`
+}
+
+type Parallel struct {
+	Matrix []map[string][]string `yaml:"matrix"`
+}
+
+type Artifacts struct {
+	When     string   `yaml:"when,omitempty"`
+	Paths    []string `yaml:"paths,omitempty"`
+	ExpireIn string   `yaml:"expire_in,omitempty"`
+	Reports  *Reports `yaml:"reports,omitempty"`
+}
+
+type Reports struct {
+	JUnit          []string        `yaml:"junit,omitempty"`
+	CoverageReport *CoverageReport `yaml:"coverage_report,omitempty"`
+}
+
+type CoverageReport struct {
+	CoverageFormat string `yaml:"coverage_format"`
+	Path           string `yaml:"path"`
+}
+
+// TagRule is a rule which matches v* tags only
+var TagRule = Rule{If: `$CI_COMMIT_TAG =~ /^v/`}
+
+// New returns a Pipeline with the stages in order
+func New(stages ...string) *Pipeline {
+	return &Pipeline{Stages: stages}
+}
+
+// AddStage adds a stage to the end of the pipeline if it does not already exist
+func (p *Pipeline) AddStage(stage string) *Pipeline {
+	for _, s := range p.Stages {
+		if s == stage {
+			return p
+		}
+	}
+	p.Stages = append(p.Stages, stage)
+	return p
+}
+
+// AddJob adds a job to the pipeline
+func (p *Pipeline) AddJob(name string, job *Job) *Pipeline {
+	p.Jobs = append(p.Jobs, yaml.MapItem{Key: name, Value: job})
+	return p
+}
+
+// JobNames returns the names of the jobs in a stage
+func (p *Pipeline) JobNames(stage string) []string {
+	var names []string
+	for _, j := range p.Jobs {
+		if j.Value.(*Job).Stage == stage {
+			names = append(names, j.Key.(string))
+		}
+	}
+	return names
+}
+
+// MarshalYAML places the jobs at the top level after the other keys
+func (p *Pipeline) MarshalYAML() (interface{}, error) {
+	m := yaml.MapSlice{{Key: "stages", Value: p.Stages}}
+	if p.Default != nil {
+		m = append(m, yaml.MapItem{Key: "default", Value: p.Default})
+	}
+	if len(p.Variables) > 0 {
+		m = append(m, yaml.MapItem{Key: "variables", Value: p.Variables})
+	}
+	return append(m, p.Jobs...), nil
+}
+
+// Build returns the pipeline as yaml
+func (p *Pipeline) Build() (string, error) {
+	b, err := yaml.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return "# Generated GitLab CI pipeline\n" + string(b), nil
+}
+
+// NewJob returns a job in a stage running a script
+func NewJob(stage string, script ...string) *Job {
+	return &Job{Stage: stage, Script: script}
+}
+
+// Need adds jobs this one needs
+func (j *Job) Need(jobs ...string) *Job {
+	for _, n := range jobs {
+		j.Needs = append(j.Needs, n)
+	}
+	return j
+}
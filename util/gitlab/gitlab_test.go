@@ -0,0 +1,82 @@
+package gitlab
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPipeline_Build(t *testing.T) {
+	p := New("init", "test").
+		AddStage("build").
+		AddStage("test")
+	p.Default = &Default{
+		Image: "golang:1.22",
+		Cache: &Cache{Key: "go-mod", Paths: []string{".go/pkg/mod"}},
+	}
+	p.Variables = map[string]string{"GOPATH": "$CI_PROJECT_DIR/.go"}
+
+	p.AddJob("init", &Job{
+		Stage:     "init",
+		Script:    []string{"make clean init"},
+		Artifacts: &Artifacts{Paths: []string{"build", "Makefile.gen"}},
+	})
+	p.AddJob("test", NewJob("test", "make test").Need("init"))
+
+	build := NewJob("build", "make -f Makefile.gen ${PLATFORM}").Need("init")
+	build.Parallel = &Parallel{Matrix: []map[string][]string{{"PLATFORM": {"linux_amd64", "linux_arm64"}}}}
+	build.Rules = []Rule{TagRule}
+	p.AddJob("build", build)
+
+	want := `# Generated GitLab CI pipeline
+stages:
+- init
+- test
+- build
+default:
+  image: golang:1.22
+  cache:
+    key: go-mod
+    paths:
+    - .go/pkg/mod
+variables:
+  GOPATH: $CI_PROJECT_DIR/.go
+init:
+  stage: init
+  script:
+  - make clean init
+  artifacts:
+    paths:
+    - build
+    - Makefile.gen
+test:
+  stage: test
+  needs:
+  - init
+  script:
+  - make test
+build:
+  stage: build
+  needs:
+  - init
+  rules:
+  - if: $CI_COMMIT_TAG =~ /^v/
+  parallel:
+    matrix:
+    - PLATFORM:
+      - linux_amd64
+      - linux_arm64
+  script:
+  - make -f Makefile.gen ${PLATFORM}
+`
+	got, err := p.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+
+	if names := p.JobNames("test"); !slices.Equal(names, []string{"test"}) {
+		t.Errorf("JobNames(test) = %v", names)
+	}
+}
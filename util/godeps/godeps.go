@@ -0,0 +1,269 @@
+package godeps
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Package is the subset of "go list -json" we need
+type Package struct {
+	Dir            string
+	ImportPath     string
+	Imports        []string // Imports for the host platform
+	GoFiles        []string
+	CgoFiles       []string
+	IgnoredGoFiles []string // Includes files for other platforms so those are dependencies too
+	EmbedFiles     []string
+	Module         *struct {
+		Path string
+		Main bool
+	}
+	allImports []string // Imports for every platform, see imports()
+}
+
+// IsMain returns true if the package is within the main module
+func (p *Package) IsMain() bool {
+	return p.Module != nil && p.Module.Main
+}
+
+// Files returns the source files of the package relative to dir
+func (p *Package) Files(dir string) []string {
+	var files []string
+	for _, l := range [][]string{p.GoFiles, p.CgoFiles, p.IgnoredGoFiles, p.EmbedFiles} {
+		for _, f := range l {
+			if strings.HasSuffix(f, "_test.go") {
+				continue
+			}
+			if rel, err := filepath.Rel(dir, filepath.Join(p.Dir, f)); err == nil {
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+	}
+	return files
+}
+
+// imports returns the packages imported by the package for every platform.
+// go list only resolves the imports for the host platform, so the files for the others are parsed.
+func (p *Package) imports() []string {
+	if p.allImports != nil {
+		return p.allImports
+	}
+
+	imports := append([]string{}, p.Imports...)
+	for _, f := range p.IgnoredGoFiles {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(p.Dir, f), nil, parser.ImportsOnly)
+		if err != nil {
+			// Not for us to report, go build will if it matters
+			continue
+		}
+		for _, i := range file.Imports {
+			if path, err := strconv.Unquote(i.Path.Value); err == nil {
+				imports = append(imports, path)
+			}
+		}
+	}
+
+	p.allImports = imports
+	return imports
+}
+
+// addMissing adds the main module packages imported by those in byPath but not within it.
+// go list ./... skips a package without files for the host platform, e.g. one only imported on windows.
+func addMissing(byPath map[string]*Package) error {
+	modules := make(map[string]bool)
+	for _, p := range byPath {
+		modules[p.Module.Path] = true
+	}
+
+	listed := make(map[string]bool)
+	for {
+		var missing []string
+		for _, p := range byPath {
+			for _, i := range p.imports() {
+				if byPath[i] == nil && !listed[i] && inModule(i, modules) {
+					listed[i] = true
+					missing = append(missing, i)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		pkgs, err := list(missing...)
+		if err != nil {
+			return err
+		}
+		for _, p := range pkgs {
+			if p.IsMain() {
+				byPath[p.ImportPath] = p
+			}
+		}
+	}
+}
+
+// inModule returns true if the import path is within one of the modules
+func inModule(path string, modules map[string]bool) bool {
+	for m := range modules {
+		if path == m || strings.HasPrefix(path, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// deps returns the package with the import path and the packages in byPath it depends on
+func deps(path string, byPath map[string]*Package) []*Package {
+	var result []*Package
+	seen := map[string]bool{path: true}
+	queue := []string{path}
+	for len(queue) > 0 {
+		p, ok := byPath[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+
+		result = append(result, p)
+		for _, i := range p.imports() {
+			if !seen[i] {
+				seen[i] = true
+				queue = append(queue, i)
+			}
+		}
+	}
+	return result
+}
+
+func list(args ...string) ([]*Package, error) {
+	var buf, errBuf bytes.Buffer
+	cmd := exec.Command("go", append([]string{"list", "-e", "-json"}, args...)...)
+	cmd.Stdout = &buf
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err != nil {
+		return nil, errors.New(strings.TrimSpace(errBuf.String()))
+	}
+
+	var pkgs []*Package
+	dec := json.NewDecoder(&buf)
+	for {
+		p := &Package{}
+		err := dec.Decode(p)
+		if err == io.EOF {
+			return pkgs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+}
+
+// Sources returns the files each package depends on within the main module,
+// keyed by the package directory relative to the current directory, e.g. "tools/build/bin".
+// go.mod and go.sum, if present, are included as dependencies of every package.
+//
+// Dependencies are resolved for every platform, so a file for another platform,
+// or a package imported only by one, is included.
+func Sources(pkgs ...string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	if len(pkgs) == 0 {
+		return result, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	// The requested packages with their dependencies
+	roots, err := list(pkgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	byPath, err := mainPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	common := moduleFiles()
+
+	for _, root := range roots {
+		files := append([]string{}, common...)
+		for _, p := range deps(root.ImportPath, byPath) {
+			files = append(files, p.Files(dir)...)
+		}
+		sort.Strings(files)
+
+		rel, err := filepath.Rel(dir, root.Dir)
+		if err != nil {
+			return nil, err
+		}
+		result[filepath.ToSlash(rel)] = files
+	}
+
+	return result, nil
+}
+
+// moduleFiles returns go.mod and go.sum if they exist
+func moduleFiles() []string {
+	var files []string
+	for _, f := range []string{"go.mod", "go.sum"} {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// ModuleSources returns every source file in the main module, plus go.mod and go.sum
+func ModuleSources() ([]string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	byPath, err := mainPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	files := moduleFiles()
+	for _, p := range byPath {
+		files = append(files, p.Files(dir)...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mainPackages returns every package in the main module by import path
+func mainPackages() (map[string]*Package, error) {
+	all, err := list("./...")
+	if err != nil {
+		return nil, err
+	}
+
+	byPath := make(map[string]*Package)
+	for _, p := range all {
+		if p.IsMain() {
+			byPath[p.ImportPath] = p
+		}
+	}
+
+	return byPath, addMissing(byPath)
+}
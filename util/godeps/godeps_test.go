@@ -0,0 +1,69 @@
+package godeps
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testModule creates a module in a temporary directory, changing to it
+func testModule(t *testing.T, files map[string]string) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	files["go.mod"] = "module example.com/m\n\ngo 1.24\n"
+	for name, content := range files {
+		fileName := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	testModule(t, map[string]string{
+		"tools/a/bin/main.go": "package main\n\nimport _ \"example.com/m/lib\"\n\nfunc main() {}\n",
+		"lib/lib.go":          "package lib\n",
+		"lib/lib_test.go":     "package lib\n",
+		// A file for another platform than the host, as no test runs on plan9
+		"lib/lib_plan9.go": "package lib\n\nimport _ \"example.com/m/plan9only\"\n",
+		// A package only imported on that platform and whose files are all for it
+		"plan9only/p.go": "//go:build plan9\n\npackage plan9only\n",
+		"other/other.go": "package other\n",
+	})
+
+	sources, err := Sources("./tools/a/bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"go.mod", "lib/lib.go", "lib/lib_plan9.go", "plan9only/p.go", "tools/a/bin/main.go"}
+	if got := sources["tools/a/bin"]; !slices.Equal(got, want) {
+		t.Errorf("Sources() = %v, want %v", got, want)
+	}
+}
+
+func TestModuleSources(t *testing.T) {
+	testModule(t, map[string]string{
+		"tools/a/bin/main.go": "package main\n\nfunc main() {}\n",
+		"lib/lib.go":          "package lib\n",
+		"lib/lib_plan9.go":    "package lib\n\nimport _ \"example.com/m/plan9only\"\n",
+		"lib/lib_test.go":     "package lib\n",
+		"plan9only/p.go":      "//go:build plan9\n\npackage plan9only\n",
+	})
+
+	files, err := ModuleSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"go.mod", "lib/lib.go", "lib/lib_plan9.go", "plan9only/p.go", "tools/a/bin/main.go"}
+	if !slices.Equal(files, want) {
+		t.Errorf("ModuleSources() = %v, want %v", files, want)
+	}
+}
@@ -0,0 +1,123 @@
+package gotest
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+	SystemOut string      `xml:"system-out,omitempty"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report in JUnit XML format, with a testsuite per package.
+// A package which failed without a failing test, e.g. it did not build, has a failing testcase named after it.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suites := junitSuites{}
+	var total float64
+
+	for _, p := range r.Packages {
+		suite := junitSuite{
+			Name:      p.Name,
+			Time:      formatSeconds(p.Elapsed.Seconds()),
+			SystemOut: p.Output.String(),
+		}
+		if !p.Start.IsZero() {
+			suite.Timestamp = p.Start.Format("2006-01-02T15:04:05")
+		}
+
+		for _, t := range p.Tests {
+			c := junitCase{
+				ClassName: p.Name,
+				Name:      t.Name,
+				Time:      formatSeconds(t.Elapsed.Seconds()),
+			}
+			switch t.Result {
+			case Fail:
+				c.Failure = &junitMessage{Message: "Failed", Body: t.Output.String()}
+				suite.Failures++
+			case Skip:
+				c.Skipped = &junitMessage{Message: skipMessage(t.Output.String())}
+				suite.Skipped++
+			default:
+				c.SystemOut = t.Output.String()
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+
+		if p.Result == Fail && suite.Failures == 0 {
+			suite.Cases = append(suite.Cases, junitCase{
+				ClassName: p.Name,
+				Name:      p.Name,
+				Time:      suite.Time,
+				Failure:   &junitMessage{Message: "Failed", Body: p.Output.String()},
+			})
+			suite.Failures++
+		}
+
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		total += p.Elapsed.Seconds()
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = formatSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// skipMessage returns the reason a test was skipped from its output
+func skipMessage(output string) string {
+	for _, l := range strings.Split(output, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "=== ") && !strings.HasPrefix(l, "--- ") {
+			return l
+		}
+	}
+	return "Skipped"
+}
+
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
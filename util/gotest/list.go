@@ -0,0 +1,32 @@
+package gotest
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// ParseList parses the output of go test -list, returning the names listed in each package
+func ParseList(r io.Reader) (map[string][]string, error) {
+	list := make(map[string][]string)
+	var names []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		switch {
+		case len(f) == 0:
+
+		// ok  	example.com/m	0.002s or ?   	example.com/m	[no test files]
+		case (f[0] == "ok" || f[0] == "?") && len(f) > 1:
+			if len(names) > 0 {
+				list[f[1]] = names
+			}
+			names = nil
+
+		case len(f) == 1:
+			names = append(names, f[0])
+		}
+	}
+	return list, scanner.Err()
+}
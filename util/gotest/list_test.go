@@ -0,0 +1,30 @@
+package gotest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	list, err := ParseList(strings.NewReader(`FuzzParse
+FuzzCompare
+ok  	example.com/m/a	0.002s
+?   	example.com/m/b	[no test files]
+ok  	example.com/m/c	0.001s
+FuzzDecode
+ok  	example.com/m/d	0.003s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 2 {
+		t.Fatalf("got %v, want 2 packages", list)
+	}
+	if a := list["example.com/m/a"]; len(a) != 2 || a[0] != "FuzzParse" || a[1] != "FuzzCompare" {
+		t.Errorf("got %v, want FuzzParse FuzzCompare", a)
+	}
+	if d := list["example.com/m/d"]; len(d) != 1 || d[0] != "FuzzDecode" {
+		t.Errorf("got %v, want FuzzDecode", d)
+	}
+}
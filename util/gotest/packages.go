@@ -0,0 +1,56 @@
+package gotest
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchPackage returns true if a package matches a pattern.
+// The pattern is either an import path or relative to the module, e.g. util/cache,
+// and may end in /... to match the package and those under it, or contain path.Match wildcards.
+func MatchPackage(pattern, module, pkg string) bool {
+	pattern = ResolvePackage(pattern, module)
+
+	if p, found := strings.CutSuffix(pattern, "/..."); found {
+		return pkg == p || strings.HasPrefix(pkg, p+"/")
+	}
+
+	matched, _ := path.Match(pattern, pkg)
+	return matched
+}
+
+// ResolvePackage returns the import path of a package which may be relative to the module
+func ResolvePackage(name, module string) string {
+	name = strings.TrimPrefix(name, "./")
+	switch {
+	case name == "." || name == "":
+		return module
+	case name == "...":
+		return module + "/..."
+	case name != module && !strings.HasPrefix(name, module+"/"):
+		return module + "/" + name
+	default:
+		return name
+	}
+}
+
+// FilterPackages returns the packages which match one of include, or all if include is empty,
+// and none of exclude
+func FilterPackages(packages, include, exclude []string, module string) []string {
+	var a []string
+	for _, pkg := range packages {
+		if (len(include) == 0 || matchAny(include, module, pkg)) && !matchAny(exclude, module, pkg) {
+			a = append(a, pkg)
+		}
+	}
+	return a
+}
+
+func matchAny(patterns []string, module, pkg string) bool {
+	for _, p := range patterns {
+		if MatchPackage(p, module, pkg) {
+			return true
+		}
+	}
+	return false
+}
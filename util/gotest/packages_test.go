@@ -0,0 +1,30 @@
+package gotest
+
+import "testing"
+
+func TestMatchPackage(t *testing.T) {
+	const module = "example.com/m"
+	tests := []struct {
+		pattern string
+		pkg     string
+		want    bool
+	}{
+		{".", "example.com/m", true},
+		{".", "example.com/m/util", false},
+		{"./...", "example.com/m/util", true},
+		{"util", "example.com/m/util", true},
+		{"util", "example.com/m/util/cache", false},
+		{"util/...", "example.com/m/util", true},
+		{"util/...", "example.com/m/util/cache", true},
+		{"util/...", "example.com/m/utility", false},
+		{"example.com/m/util/*", "example.com/m/util/cache", true},
+		{"tools/*/bin", "example.com/m/tools/build/bin", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.pkg, func(t *testing.T) {
+			if got := MatchPackage(tt.pattern, module, tt.pkg); got != tt.want {
+				t.Errorf("MatchPackage(%q, %q) = %v, want %v", tt.pattern, tt.pkg, got, tt.want)
+			}
+		})
+	}
+}
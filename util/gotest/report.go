@@ -0,0 +1,236 @@
+package gotest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event is a line of output from go test -json, see go doc test2json
+type Event struct {
+	Time        time.Time `json:"Time"`
+	Action      string    `json:"Action"`
+	Package     string    `json:"Package"`
+	Test        string    `json:"Test"`
+	Elapsed     float64   `json:"Elapsed"`
+	Output      string    `json:"Output"`
+	ImportPath  string    `json:"ImportPath"`  // build-output & build-fail events
+	FailedBuild string    `json:"FailedBuild"` // Package which failed to build
+}
+
+// Results of a test or package
+const (
+	Pass = "pass"
+	Fail = "fail"
+	Skip = "skip"
+)
+
+// Report is the result of a go test run
+type Report struct {
+	Packages []*Package
+	Output   strings.Builder // All output, as go test would have printed without -json
+	Seed     string          // Seed of -shuffle, if used
+	Elapsed  time.Duration   // Wall clock time of the run, set by the caller as packages run in parallel
+	packages map[string]*Package
+	builds   map[string]*strings.Builder // Build output by import path
+}
+
+// Package is the result of testing a package
+type Package struct {
+	Name    string
+	Result  string
+	Elapsed time.Duration
+	Start   time.Time
+	Output  strings.Builder // Output not from a test
+	Tests   []*Test
+	tests   map[string]*Test
+}
+
+// Test is the result of a single test
+type Test struct {
+	Name    string
+	Result  string
+	Elapsed time.Duration
+	Output  strings.Builder
+	Flaky   bool // Failed then passed when run again
+}
+
+// NewReport returns an empty Report
+func NewReport() *Report {
+	return &Report{
+		packages: make(map[string]*Package),
+		builds:   make(map[string]*strings.Builder),
+	}
+}
+
+// Parse reads the output of go test -json.
+// Lines which are not json, e.g. from a failed build in older go versions, are kept as output.
+func Parse(r io.Reader) (*Report, error) {
+	report := NewReport()
+	return report, report.Read(r)
+}
+
+// Read adds the output of go test -json to the report, e.g. when go test is run more than once
+func (r *Report) Read(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		e := Event{}
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil {
+			r.Output.Write(line)
+			r.Output.WriteString("\n")
+			continue
+		}
+
+		r.add(e)
+	}
+
+	sort.SliceStable(r.Packages, func(i, j int) bool {
+		return r.Packages[i].Name < r.Packages[j].Name
+	})
+
+	return scanner.Err()
+}
+
+// add an event to the report
+func (r *Report) add(e Event) {
+	switch e.Action {
+	case "build-output":
+		b := r.builds[e.ImportPath]
+		if b == nil {
+			b = &strings.Builder{}
+			r.builds[e.ImportPath] = b
+		}
+		b.WriteString(e.Output)
+		r.Output.WriteString(e.Output)
+		return
+
+	case "build-fail":
+		return
+	}
+
+	if e.Package == "" {
+		r.Output.WriteString(e.Output)
+		return
+	}
+
+	p := r.pkg(e.Package)
+
+	if e.Test == "" {
+		switch e.Action {
+		case "start":
+			p.Start = e.Time
+		case "output":
+			p.Output.WriteString(e.Output)
+			r.Output.WriteString(e.Output)
+		case Pass, Fail, Skip:
+			p.Result = e.Action
+			p.Elapsed = seconds(e.Elapsed)
+			if e.FailedBuild != "" {
+				if b := r.builds[e.FailedBuild]; b != nil {
+					p.Output.WriteString(b.String())
+				}
+			}
+		}
+		return
+	}
+
+	t := p.test(e.Test)
+	switch e.Action {
+	case "output":
+		t.Output.WriteString(e.Output)
+		r.Output.WriteString(e.Output)
+	case Pass, Fail, Skip:
+		t.Result = e.Action
+		t.Elapsed = seconds(e.Elapsed)
+	}
+}
+
+func (r *Report) pkg(name string) *Package {
+	p := r.packages[name]
+	if p == nil {
+		p = &Package{Name: name, tests: make(map[string]*Test)}
+		r.packages[name] = p
+		r.Packages = append(r.Packages, p)
+	}
+	return p
+}
+
+func (p *Package) test(name string) *Test {
+	t := p.tests[name]
+	if t == nil {
+		t = &Test{Name: name}
+		p.tests[name] = t
+		p.Tests = append(p.Tests, t)
+	}
+	return t
+}
+
+// Failed returns true if any package failed
+func (r *Report) Failed() bool {
+	for _, p := range r.Packages {
+		if p.Result == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// FailedTests returns the top level tests which failed in each package, e.g. to run them again.
+// Subtests are not included as they run with their parent.
+func (r *Report) FailedTests() map[string][]string {
+	failed := make(map[string][]string)
+	for _, p := range r.Packages {
+		for _, t := range p.Tests {
+			if t.Result == Fail && !strings.Contains(t.Name, "/") {
+				failed[p.Name] = append(failed[p.Name], t.Name)
+			}
+		}
+	}
+	return failed
+}
+
+// MarkFlaky marks the failed tests which passed in a re-run as flaky, returning them.
+// A package whose failed tests are all flaky then passes.
+func (r *Report) MarkFlaky(rerun *Report) []*Test {
+	var flaky []*Test
+	for _, rp := range rerun.Packages {
+		p := r.packages[rp.Name]
+		if p == nil {
+			continue
+		}
+
+		for _, rt := range rp.Tests {
+			if t := p.tests[rt.Name]; t != nil && t.Result == Fail && rt.Result == Pass {
+				t.Result = Pass
+				t.Flaky = true
+				flaky = append(flaky, t)
+			}
+		}
+
+		if p.Result == Fail && p.Count(Fail) == 0 && rp.Result == Pass {
+			p.Result = Pass
+		}
+	}
+	return flaky
+}
+
+// Count returns the number of tests with a result
+func (p *Package) Count(result string) int {
+	c := 0
+	for _, t := range p.Tests {
+		if t.Result == result {
+			c++
+		}
+	}
+	return c
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,128 @@
+package gotest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testOutput = `{"Action":"start","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/a","Test":"TestPass"}
+{"Action":"output","Package":"example.com/a","Test":"TestPass","Output":"=== RUN   TestPass\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestPass","Elapsed":0.5}
+{"Action":"run","Package":"example.com/a","Test":"TestFail"}
+{"Action":"output","Package":"example.com/a","Test":"TestFail","Output":"    a_test.go:10: failed\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestFail","Elapsed":0.25}
+{"Action":"run","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"fail","Package":"example.com/a","Elapsed":1}
+{"ImportPath":"example.com/b","Action":"build-output","Output":"b.go:3: undefined: x\n"}
+{"ImportPath":"example.com/b","Action":"build-fail"}
+{"Action":"start","Package":"example.com/b"}
+{"Action":"fail","Package":"example.com/b","Elapsed":0,"FailedBuild":"example.com/b"}
+not json
+`
+
+func TestParse(t *testing.T) {
+	r, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.Failed() {
+		t.Error("expected report to have failed")
+	}
+
+	if len(r.Packages) != 2 {
+		t.Fatalf("got %d packages, want 2", len(r.Packages))
+	}
+
+	a := r.Packages[0]
+	if a.Name != "example.com/a" || a.Result != Fail {
+		t.Errorf("got %s %s, want example.com/a fail", a.Name, a.Result)
+	}
+	for _, result := range []string{Pass, Fail, Skip} {
+		if c := a.Count(result); c != 1 {
+			t.Errorf("got %d %s, want 1", c, result)
+		}
+	}
+
+	b := r.Packages[1]
+	if len(b.Tests) != 0 || !strings.Contains(b.Output.String(), "undefined: x") {
+		t.Errorf("expected build failure output in %q", b.Output.String())
+	}
+
+	if !strings.Contains(r.Output.String(), "not json") {
+		t.Error("expected non json lines in output")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	r, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := r.WriteJUnit(&sb); err != nil {
+		t.Fatal(err)
+	}
+	s := sb.String()
+
+	for _, want := range []string{
+		`<testsuite name="example.com/a" tests="3" failures="1" skipped="1" time="1.000">`,
+		`<testcase classname="example.com/a" name="TestFail" time="0.250">`,
+		`<testcase classname="example.com/b" name="example.com/b" time="0.000">`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %s in\n%s", want, s)
+		}
+	}
+}
+
+func TestReport_Summary(t *testing.T) {
+	r, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Elapsed = 1500 * time.Millisecond
+
+	s := r.Summary(1)
+	if s.Elapsed != 1.5 {
+		t.Errorf("got elapsed %v, want the wall clock time 1.5", s.Elapsed)
+	}
+	if s.Result != Fail || s.Passed != 1 || s.Failed != 1 || s.Skipped != 1 {
+		t.Errorf("got %s %d/%d/%d, want fail 1/1/1", s.Result, s.Passed, s.Failed, s.Skipped)
+	}
+
+	if len(s.Slowest) != 1 || s.Slowest[0].Name != "TestPass" || s.Slowest[0].Package != "example.com/a" {
+		t.Errorf("got slowest %v, want example.com/a TestPass", s.Slowest)
+	}
+}
+
+func TestReport_MarkFlaky(t *testing.T) {
+	r, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if failed := r.FailedTests(); len(failed) != 1 || len(failed["example.com/a"]) != 1 {
+		t.Fatalf("got failed %v, want example.com/a TestFail", failed)
+	}
+
+	rerun, err := Parse(strings.NewReader(`{"Action":"pass","Package":"example.com/a","Test":"TestFail","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/a","Elapsed":0.1}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flaky := r.MarkFlaky(rerun); len(flaky) != 1 || flaky[0].Name != "TestFail" {
+		t.Errorf("got flaky %v, want TestFail", flaky)
+	}
+
+	if p := r.Packages[0]; p.Result != Pass {
+		t.Errorf("got %s, want example.com/a to pass", p.Result)
+	}
+}
@@ -0,0 +1,170 @@
+package gotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary is the machine-readable summary of a Report
+type Summary struct {
+	Result   string           `json:"result"`
+	Passed   int              `json:"passed"`
+	Failed   int              `json:"failed"`
+	Skipped  int              `json:"skipped"`
+	Elapsed  float64          `json:"elapsed"`        // Seconds, wall clock time of the run
+	Seed     string           `json:"seed,omitempty"` // Seed of -shuffle
+	Packages []PackageSummary `json:"packages"`
+	Slowest  []TestSummary    `json:"slowest,omitempty"`
+	Flaky    []TestSummary    `json:"flaky,omitempty"` // Tests which failed then passed when run again
+}
+
+// PackageSummary is the summary of a package
+type PackageSummary struct {
+	Name    string        `json:"name"`
+	Result  string        `json:"result"`
+	Passed  int           `json:"passed"`
+	Failed  int           `json:"failed"`
+	Skipped int           `json:"skipped"`
+	Elapsed float64       `json:"elapsed"` // Seconds
+	Tests   []TestSummary `json:"tests,omitempty"`
+}
+
+// TestSummary is the summary of a test
+type TestSummary struct {
+	Package string  `json:"package,omitempty"` // Only set in Summary.Slowest
+	Name    string  `json:"name"`
+	Result  string  `json:"result"`
+	Elapsed float64 `json:"elapsed"` // Seconds
+	Flaky   bool    `json:"flaky,omitempty"`
+}
+
+// Summary returns the summary of the report including the slowest tests
+func (r *Report) Summary(slowest int) *Summary {
+	s := &Summary{Result: Pass, Seed: r.Seed, Elapsed: seconds3(r.Elapsed)}
+	if r.Failed() {
+		s.Result = Fail
+	}
+
+	var tests []TestSummary
+	for _, p := range r.Packages {
+		ps := PackageSummary{
+			Name:    p.Name,
+			Result:  p.Result,
+			Passed:  p.Count(Pass),
+			Failed:  p.Count(Fail),
+			Skipped: p.Count(Skip),
+			Elapsed: seconds3(p.Elapsed),
+		}
+
+		for _, t := range p.Tests {
+			ts := TestSummary{Name: t.Name, Result: t.Result, Elapsed: seconds3(t.Elapsed), Flaky: t.Flaky}
+			ps.Tests = append(ps.Tests, ts)
+
+			if t.Flaky {
+				s.Flaky = append(s.Flaky, TestSummary{Package: p.Name, Name: t.Name, Result: t.Result, Elapsed: ts.Elapsed, Flaky: true})
+			}
+
+			if t.Elapsed > 0 {
+				ts.Package = p.Name
+				tests = append(tests, ts)
+			}
+		}
+
+		s.Passed += ps.Passed
+		s.Failed += ps.Failed
+		s.Skipped += ps.Skipped
+		s.Packages = append(s.Packages, ps)
+	}
+
+	sort.SliceStable(tests, func(i, j int) bool {
+		return tests[i].Elapsed > tests[j].Elapsed
+	})
+	if len(tests) > slowest {
+		tests = tests[:slowest]
+	}
+	s.Slowest = tests
+
+	return s
+}
+
+// seconds3 returns a duration in seconds to the nearest millisecond
+func seconds3(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
+
+// WriteJSON writes the summary as json
+func (s *Summary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteTable writes the summary as a table of the packages which have tests or failed,
+// followed by the slowest tests
+func (s *Summary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "Package\tResult\tPass\tFail\tSkip\tTime")
+	for _, p := range s.Packages {
+		if len(p.Tests) > 0 || p.Result == Fail {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.3fs\n", p.Name, p.Result, p.Passed, p.Failed, p.Skipped, p.Elapsed)
+		}
+	}
+	_, _ = fmt.Fprintf(tw, "Total\t%s\t%d\t%d\t%d\t%.3fs\n", s.Result, s.Passed, s.Failed, s.Skipped, s.Elapsed)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(s.Slowest) > 0 {
+		_, _ = fmt.Fprintln(w, "\nSlowest tests:")
+		for _, t := range s.Slowest {
+			_, _ = fmt.Fprintf(tw, "%.3fs\t%s\t%s\n", t.Elapsed, t.Package, t.Name)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(s.Flaky) > 0 {
+		_, _ = fmt.Fprintln(w, "\nFlaky tests, failed then passed when run again:")
+		for _, t := range s.Flaky {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\n", t.Package, t.Name)
+		}
+	}
+
+	if s.Seed != "" {
+		_, _ = fmt.Fprintf(tw, "\nShuffle seed %s\n", s.Seed)
+	}
+	return tw.Flush()
+}
+
+// WriteFailures writes the output of each failed test,
+// or of the package when it failed without a failing test, e.g. it did not build
+func (r *Report) WriteFailures(w io.Writer) error {
+	for _, p := range r.Packages {
+		if p.Result != Fail {
+			continue
+		}
+
+		failed := false
+		for _, t := range p.Tests {
+			if t.Result == Fail {
+				failed = true
+				if _, err := io.WriteString(w, t.Output.String()); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !failed {
+			if _, err := io.WriteString(w, p.Output.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 type Builder interface {
 	Begin(string, ...any) Builder
+	Block(string, string) Builder
 	Array() Builder
 	Separator(string) Builder
 	Line(string, ...any) Builder
@@ -24,16 +25,50 @@ type Builder interface {
 	Parallel() Builder
 
 	Sort() Builder
+
+	// Declarative pipelines
+
+	Declarative() Builder
+	IsDeclarative() bool
+	Pipeline(string) Builder
+	Section(string) Builder
+	When(string) Builder
+	MatrixStage(string) Builder
+	Axis(string, ...string) Builder
+	Exclude() Builder
+
+	StageOptions(name string, timeout, retry int) Builder
+	Agent(string) Builder
+
+	Stash(name, includes string) Builder
+	Unstash(string) Builder
+	Dir(string) Builder
+	Timeout(int) Builder
+
+	Script() Builder
+	JUnit(string) Builder
+	RecordCoverage(string) Builder
 }
 
 type builder struct {
-	parent     *builder   // parent builder
-	key        string     // Used for sorting stages
-	parallel   bool       // Used for parallel blocks
-	start      string     // Start of block, e.g. node("")
-	separator  string     // Separator between lines & children, e.g. ","
-	children   []*builder // Child builders
-	terminator string     // Terminator e.g. "}" or "])"
+	parent      *builder                // parent builder
+	key         string                  // Used for sorting stages
+	parallel    bool                    // Used for parallel blocks
+	start       string                  // Start of block, e.g. node("")
+	separator   string                  // Separator between lines & children, e.g. ","
+	children    []*builder              // Child builders
+	terminator  string                  // Terminator e.g. "}" or "])"
+	declarative bool                    // Generating a declarative pipeline
+	options     map[string]stageOptions // Options applied to stages by name, root only
+	stage       *builder                // The stage containing a declarative steps block
+	agent       *builder                // agent directive of a declarative stage
+	when        *builder                // when directive of a declarative stage
+}
+
+// stageOptions are applied to a stage when it is created
+type stageOptions struct {
+	timeout int // Timeout in minutes, 0 for none
+	retry   int // Number of times to retry, 0 for none
 }
 
 func New() Builder {
@@ -51,8 +86,9 @@ func (b *builder) Separator(s string) Builder {
 
 func (b *builder) Line(f string, a ...any) Builder {
 	c := &builder{
-		parent: b,
-		start:  fmt.Sprintf(f, a...),
+		parent:      b,
+		start:       fmt.Sprintf(f, a...),
+		declarative: b.declarative,
 	}
 	b.children = append(b.children, c)
 	return b
@@ -66,10 +102,10 @@ func (b *builder) Begin(f string, a ...any) Builder {
 	return b.begin("", f, a...)
 }
 
-func (b *builder) begin(key, f string, a ...any) Builder {
+func (b *builder) begin(key, f string, a ...any) *builder {
 	s := fmt.Sprintf(f, a...)
 
-	c := &builder{parent: b, start: s, key: key}
+	c := &builder{parent: b, start: s, key: key, declarative: b.declarative}
 
 	switch {
 	case strings.HasSuffix(s, "(["):
@@ -83,6 +119,14 @@ func (b *builder) begin(key, f string, a ...any) Builder {
 	return c
 }
 
+// Block adds a block with an explicit start and terminator.
+// This is for blocks that Begin cannot handle, e.g. "} finally {" which continues an earlier block.
+func (b *builder) Block(start, terminator string) Builder {
+	c := &builder{parent: b, start: start, terminator: terminator, declarative: b.declarative}
+	b.children = append(b.children, c)
+	return c
+}
+
 func (b *builder) End() Builder {
 	if b.parent == nil {
 		return b
@@ -126,6 +170,13 @@ func (b *builder) buildInner(nest int, startEnd bool, a []string) []string {
 			}
 
 		} else {
+			// The directives of a declarative stage precede its other children
+			for _, c := range []*builder{b.agent, b.when} {
+				if c != nil {
+					a1 = c.build(nest+1, a1)
+				}
+			}
+
 			for _, c := range b.children {
 				a1 = c.build(nest+1, a1)
 			}
@@ -157,8 +208,53 @@ func (b *builder) Node(s string) Builder {
 	return b.Begin("node(%q) {", s)
 }
 
+// Stage adds a stage.
+// For declarative pipelines this returns the steps block within the stage.
 func (b *builder) Stage(s string) Builder {
-	return b.begin(s, "stage(%q) {", s)
+	stage := b.begin(s, "stage(%q) {", s)
+	opts := b.root().options[s]
+
+	if b.declarative {
+		if opts.timeout > 0 || opts.retry > 0 {
+			o := stage.Begin("options {")
+			if opts.timeout > 0 {
+				o.Line("timeout(time: %d, unit: 'MINUTES')", opts.timeout)
+			}
+			if opts.retry > 0 {
+				o.Line("retry(%d)", opts.retry)
+			}
+		}
+		steps := stage.begin("", "steps {")
+		steps.stage = stage
+		return steps
+	}
+
+	var steps Builder = stage
+	if opts.timeout > 0 {
+		steps = steps.Timeout(opts.timeout)
+	}
+	if opts.retry > 0 {
+		steps = steps.Begin("retry(%d) {", opts.retry)
+	}
+	return steps
+}
+
+// StageOptions sets the timeout in minutes and number of retries of the stages with a name.
+// This must be set before the stages are added.
+func (b *builder) StageOptions(name string, timeout, retry int) Builder {
+	r := b.root()
+	if r.options == nil {
+		r.options = make(map[string]stageOptions)
+	}
+	r.options[name] = stageOptions{timeout: timeout, retry: retry}
+	return b
+}
+
+func (b *builder) root() *builder {
+	for b.parent != nil {
+		b = b.parent
+	}
+	return b
 }
 
 func (b *builder) Sh(f string, a ...any) Builder {
@@ -171,11 +267,12 @@ func (b *builder) ArchiveArtifacts(f string, a ...any) Builder {
 
 func (b *builder) Parallel() Builder {
 	c := &builder{
-		parent:     b,
-		parallel:   true,
-		start:      "parallel(",
-		separator:  ",",
-		terminator: ")",
+		parent:      b,
+		parallel:    true,
+		start:       "parallel(",
+		separator:   ",",
+		terminator:  ")",
+		declarative: b.declarative,
 	}
 	b.children = append(b.children, c)
 	return c
@@ -198,3 +295,130 @@ func (b *builder) Simple(p string, v ...string) Builder {
 	b.children = append(b.children, c)
 	return b
 }
+
+// Declarative marks the builder as generating a declarative pipeline
+func (b *builder) Declarative() Builder {
+	b.declarative = true
+	return b
+}
+
+func (b *builder) IsDeclarative() bool {
+	return b.declarative
+}
+
+// Pipeline adds the pipeline block of a declarative pipeline running on an agent with a label
+func (b *builder) Pipeline(label string) Builder {
+	p := b.Begin("pipeline {")
+	p.Begin("agent {").
+		Line("label %q", label)
+	return p
+}
+
+// Section adds a named block, e.g. options, stages or post
+func (b *builder) Section(name string) Builder {
+	return b.Begin("%s {", name)
+}
+
+// When adds a condition to the stage containing a steps block in a declarative pipeline.
+// All conditions must be met for the stage to run.
+func (b *builder) When(condition string) Builder {
+	if b.stage != nil {
+		if b.stage.when == nil {
+			b.stage.when = &builder{parent: b.stage, start: "when {", terminator: "}", declarative: true}
+		}
+		b.stage.when.Line("%s", condition)
+	}
+	return b
+}
+
+// Agent sets the agent of the stage containing a steps block in a declarative pipeline
+func (b *builder) Agent(label string) Builder {
+	if b.stage != nil {
+		b.stage.agent = &builder{parent: b.stage, start: "agent {", terminator: "}", declarative: true}
+		b.stage.agent.Line("label %q", label)
+	}
+	return b
+}
+
+// MatrixStage adds a stage containing a matrix, returning the matrix block
+func (b *builder) MatrixStage(s string) Builder {
+	return b.begin(s, "stage(%q) {", s).
+		Begin("matrix {")
+}
+
+// Axis adds an axis to a matrix or an exclude.
+// Within a matrix the axis is added to its axes block.
+func (b *builder) Axis(name string, values ...string) Builder {
+	parent := Builder(b)
+	if strings.HasPrefix(b.start, "matrix") {
+		parent = b.child("axes {")
+	}
+
+	parent.Begin("axis {").
+		Line("name %q", name).
+		Line("values %s", QuoteAll(values))
+	return b
+}
+
+// Exclude adds an exclude to a matrix, returning it so axes can be added to it
+func (b *builder) Exclude() Builder {
+	return b.child("excludes {").Begin("exclude {")
+}
+
+// child returns the block with the start, adding it if it does not exist
+func (b *builder) child(start string) Builder {
+	for _, c := range b.children {
+		if c.start == start {
+			return c
+		}
+	}
+	return b.Begin("%s", start)
+}
+
+// QuoteAll returns the values as a comma separated list of groovy strings
+func QuoteAll(values []string) string {
+	var a []string
+	for _, v := range values {
+		a = append(a, fmt.Sprintf("%q", v))
+	}
+	return strings.Join(a, ", ")
+}
+
+// Stash adds a stash of the files matching includes
+func (b *builder) Stash(name, includes string) Builder {
+	return b.Line("stash name: %q, includes: %q, allowEmpty: true", name, includes)
+}
+
+// Unstash restores a stash into the current directory
+func (b *builder) Unstash(name string) Builder {
+	return b.Line("unstash %q", name)
+}
+
+// Dir returns a block run within a directory
+func (b *builder) Dir(path string) Builder {
+	return b.Begin("dir(%q) {", path)
+}
+
+// Timeout returns a block which times out after a number of minutes
+func (b *builder) Timeout(minutes int) Builder {
+	return b.Begin("timeout(time: %d, unit: 'MINUTES') {", minutes)
+}
+
+// Script returns a block for scripted steps.
+// In a declarative pipeline this is a script block, otherwise the builder itself.
+func (b *builder) Script() Builder {
+	if b.declarative {
+		return b.Begin("script {")
+	}
+	return b
+}
+
+// JUnit publishes the JUnit test results matching a pattern
+func (b *builder) JUnit(pattern string) Builder {
+	return b.Line("junit allowEmptyResults: true, testResults: '%s'", pattern)
+}
+
+// RecordCoverage publishes the Cobertura coverage reports matching a pattern
+func (b *builder) RecordCoverage(pattern string) Builder {
+	return b.Line("recordCoverage tools: [[parser: 'COBERTURA', pattern: '%s']]", pattern)
+}
@@ -0,0 +1,191 @@
+package jenkinsfile
+
+import "testing"
+
+func TestBuilder_Declarative(t *testing.T) {
+	builder := New().Declarative().
+		StageOptions("Test", 30, 2)
+
+	pipeline := builder.Pipeline("go")
+	pipeline.Section("options").
+		Line("disableConcurrentBuilds()")
+
+	stages := pipeline.Section("stages")
+	stages.Stage("Init").
+		Sh("make clean init")
+
+	stages.Stage("Test").
+		Agent("linux-arm64").
+		Sh("make test").
+		JUnit("builds/go-test.xml")
+
+	fuzz := stages.Stage("Fuzz").
+		When("triggeredBy 'TimerTrigger'").
+		When("branch 'main'")
+	fuzz.Sh("make fuzz")
+	fuzz.Script().
+		Begin("if (fileExists('builds/fuzz-failed')) {").
+		Line("unstable('Fuzzing found crashers')")
+
+	pipeline.Section("post").
+		Section("always").
+		Sh("echo done")
+
+	want := `pipeline {
+  agent {
+    label "go"
+  }
+  options {
+    disableConcurrentBuilds()
+  }
+  stages {
+    stage("Init") {
+      steps {
+        sh 'make clean init'
+      }
+    }
+    stage("Test") {
+      agent {
+        label "linux-arm64"
+      }
+      options {
+        timeout(time: 30, unit: 'MINUTES')
+        retry(2)
+      }
+      steps {
+        sh 'make test'
+        junit allowEmptyResults: true, testResults: 'builds/go-test.xml'
+      }
+    }
+    stage("Fuzz") {
+      when {
+        triggeredBy 'TimerTrigger'
+        branch 'main'
+      }
+      steps {
+        sh 'make fuzz'
+        script {
+          if (fileExists('builds/fuzz-failed')) {
+            unstable('Fuzzing found crashers')
+          }
+        }
+      }
+    }
+  }
+  post {
+    always {
+      sh 'echo done'
+    }
+  }
+}`
+	if got := builder.Build(); got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuilder_StageOptions(t *testing.T) {
+	builder := New().
+		StageOptions("Test", 30, 2).
+		StageOptions("Build", 0, 3)
+
+	node := builder.Node("go")
+	node.Stage("Test").Sh("make test")
+	node.Stage("Build").Sh("make all")
+	node.Stage("Other").Sh("make other")
+
+	want := `node("go") {
+  stage("Test") {
+    timeout(time: 30, unit: 'MINUTES') {
+      retry(2) {
+        sh 'make test'
+      }
+    }
+  }
+  stage("Build") {
+    retry(3) {
+      sh 'make all'
+    }
+  }
+  stage("Other") {
+    sh 'make other'
+  }
+}`
+	if got := builder.Build(); got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestQuoteAll(t *testing.T) {
+	if got, want := QuoteAll([]string{"a", `b"c`}), `"a", "b\"c"`; got != want {
+		t.Errorf("QuoteAll() = %s, want %s", got, want)
+	}
+}
+
+func TestBuilder_Matrix(t *testing.T) {
+	builder := New().Declarative()
+	stages := builder.Section("stages")
+
+	matrix := stages.MatrixStage("Build").
+		Axis("OS", "linux", "windows").
+		Axis("ARCH", "amd64", "arm64")
+	matrix.Exclude().
+		Axis("OS", "windows").
+		Axis("ARCH", "arm64")
+	matrix.Section("stages").
+		Stage("Build").
+		Sh("make ${OS}_${ARCH}").
+		Stash("dist-${OS}_${ARCH}", "dist/**")
+
+	stages.Stage("Package").
+		Unstash("dist-linux_amd64").
+		Dir("builds/test").
+		Unstash("test")
+
+	want := `stages {
+  stage("Build") {
+    matrix {
+      axes {
+        axis {
+          name "OS"
+          values "linux", "windows"
+        }
+        axis {
+          name "ARCH"
+          values "amd64", "arm64"
+        }
+      }
+      excludes {
+        exclude {
+          axis {
+            name "OS"
+            values "windows"
+          }
+          axis {
+            name "ARCH"
+            values "arm64"
+          }
+        }
+      }
+      stages {
+        stage("Build") {
+          steps {
+            sh 'make ${OS}_${ARCH}'
+            stash name: "dist-${OS}_${ARCH}", includes: "dist/**", allowEmpty: true
+          }
+        }
+      }
+    }
+  }
+  stage("Package") {
+    steps {
+      unstash "dist-linux_amd64"
+      dir("builds/test") {
+        unstash "test"
+      }
+    }
+  }
+}`
+	if got := builder.Build(); got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
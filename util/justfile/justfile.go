@@ -0,0 +1,126 @@
+package justfile
+
+import (
+	"fmt"
+	"github.com/peter-mount/go-build/util/makefile"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Justfile renders a makefile.Graph as a justfile for https://just.systems
+type Justfile struct {
+	Graph *makefile.Graph
+	// Run is the command which runs a target with its dependencies, e.g. "./build -d builds -run".
+	// When set each target, other than files, calls it instead of running the commands itself,
+	// so only those which are out of date are run. just has no up-to-date checks of its own.
+	Run string
+}
+
+// New returns a Justfile for a Graph
+func New(g *makefile.Graph) *Justfile {
+	return &Justfile{Graph: g}
+}
+
+// Build returns the justfile
+func (j *Justfile) Build() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Generated justfile\n")
+	if j.Run == "" {
+		sb.WriteString("# just has no up-to-date checks so every recipe is run, including compiling each tool\n\n")
+	} else {
+		sb.WriteString("# Each recipe runs the rules which are out of date with " + j.Run + "\n\n")
+	}
+
+	if env := j.Graph.Env(); len(env) > 0 && j.Run == "" {
+		sort.Strings(env)
+		for _, e := range env {
+			k, v, _ := strings.Cut(e, "=")
+			_, _ = fmt.Fprintf(&sb, "export %s := %s\n", k, quote(v))
+		}
+		sb.WriteString("\n")
+	}
+
+	// The first recipe is the default
+	if j.Graph.Get("all") != nil && j.Graph.Get("default") == nil {
+		sb.WriteString("default: all\n\n")
+	}
+
+	for _, target := range j.Graph.Order {
+		switch {
+		case j.Run == "":
+			j.recipe(&sb, j.Graph.Get(target))
+		case !j.Graph.IsFile(target):
+			j.run(&sb, j.Graph.Expand(target))
+		}
+	}
+
+	return sb.String()
+}
+
+func (j *Justfile) recipe(sb *strings.Builder, node *makefile.Node) {
+	g := j.Graph
+	target := g.Expand(node.Target)
+
+	// just has no file targets so only dependencies with rules are recipes
+	var deps []string
+	for _, d := range g.Dependencies(node) {
+		if g.Get(d) != nil {
+			deps = append(deps, Name(d))
+		}
+	}
+
+	if Name(target) != target {
+		_, _ = fmt.Fprintf(sb, "# %s\n", target)
+	}
+	sb.WriteString(Name(target) + ":")
+	if len(deps) > 0 {
+		sb.WriteString(" " + strings.Join(deps, " "))
+	}
+	sb.WriteString("\n")
+
+	for _, l := range node.Commands {
+		c, ignoreError := makefile.ParseCommand(g.Expand(l))
+		if c == "" {
+			continue
+		}
+		prefix := "@"
+		if ignoreError {
+			prefix = "-@"
+		}
+		_, _ = fmt.Fprintf(sb, "    %s%s\n", prefix, escape(c))
+	}
+	sb.WriteString("\n")
+}
+
+// run writes a recipe which runs a target with Run
+func (j *Justfile) run(sb *strings.Builder, target string) {
+	_, _ = fmt.Fprintf(sb, "%s:\n    @%s %s\n\n", Name(target), escape(j.Run), target)
+}
+
+// Name returns the recipe name for a target.
+// Recipe names are identifiers so file targets like builds/linux/amd64/bin/tool become builds_linux_amd64_bin_tool
+func Name(target string) string {
+	if target != "" && !unicode.IsLetter(rune(target[0])) {
+		target = "_" + target
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, target)
+}
+
+// escape interpolation in a command, as just treats {{ }} as an expression
+func escape(s string) string {
+	return strings.ReplaceAll(s, "{{", "{{{{")
+}
+
+// quote a just string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,96 @@
+package justfile
+
+import (
+	"github.com/peter-mount/go-build/util/makefile"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"linux_amd64_tools", "linux_amd64_tools"},
+		{"builds/linux/amd64/bin/tool", "builds_linux_amd64_bin_tool"},
+		{"dist/go-build_1.0_linux_amd64.tgz", "dist_go-build_1_0_linux_amd64_tgz"},
+		{"386", "_386"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := Name(tt.target); got != tt.want {
+				t.Errorf("Name(%q) = %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+// testGraph returns the rules used by the justfile tests
+func testGraph() *makefile.Graph {
+	b := makefile.New()
+	b.SetVar("BUILD", "go run build.go")
+	b.Command("export BUILD_VERSION = \"1.0\"")
+	b.Phony("all", "init", "clean")
+	b.Rule("init").Line("@mkdir -p builds")
+	b.Rule("clean").Line("-@rm -rf builds")
+	b.Rule("all", "linux_amd64")
+	b.Rule("linux_amd64", "init", "builds/bin/tool")
+	b.Rule("builds/bin/tool", "main.go").
+		Line("@$(BUILD) -go build linux amd64 tool").
+		Line("@echo '{{ x }}'")
+	return makefile.NewGraph(b)
+}
+
+func TestJustfile_Build(t *testing.T) {
+	want := `# Generated justfile
+# just has no up-to-date checks so every recipe is run, including compiling each tool
+
+export BUILD_VERSION := "\"1.0\""
+
+default: all
+
+init:
+    @mkdir -p builds
+
+clean:
+    -@rm -rf builds
+
+all: linux_amd64
+
+linux_amd64: init builds_bin_tool
+
+# builds/bin/tool
+builds_bin_tool:
+    @go run build.go -go build linux amd64 tool
+    @echo '{{{{ x }}'
+
+`
+	if got := New(testGraph()).Build(); got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJustfile_Run(t *testing.T) {
+	want := `# Generated justfile
+# Each recipe runs the rules which are out of date with ./build -d builds -run
+
+default: all
+
+init:
+    @./build -d builds -run init
+
+clean:
+    @./build -d builds -run clean
+
+all:
+    @./build -d builds -run all
+
+linux_amd64:
+    @./build -d builds -run linux_amd64
+
+`
+	j := New(testGraph())
+	j.Run = "./build -d builds -run"
+	if got := j.Build(); got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -188,7 +188,7 @@ func (b *builder) Line(f string, a ...any) Builder {
 }
 
 func (b *builder) Echo(n string, f string, a ...any) Builder {
-	return b.Line(fmt.Sprintf(`@echo "%-10s %s";\`, n, fmt.Sprintf(f, a...)))
+	return b.Line(`@echo "%-10s %s";\`, n, fmt.Sprintf(f, a...))
 }
 
 func (b *builder) Mkdir(dirs ...string) Builder {
@@ -196,7 +196,7 @@ func (b *builder) Mkdir(dirs ...string) Builder {
 }
 
 func (b *builder) RM(dirs ...string) Builder {
-	return b.Echo("RM", strings.Join(dirs, " ")).
+	return b.Echo("RM", "%s", strings.Join(dirs, " ")).
 		Line("rm -rf %s", strings.Join(dirs, " "))
 }
 
@@ -0,0 +1,283 @@
+package makefile
+
+import (
+	"strings"
+)
+
+// Graph is the dependency graph described by a Builder.
+// It allows the rules to be executed without make or rendered for other build tools.
+type Graph struct {
+	Vars    map[string]string // Variables
+	Exports []string          // Names of exported variables, in the order they were declared
+	Phony   map[string]bool   // Phony targets
+	Nodes   map[string]*Node  // Rules by target
+	Order   []string          // Targets in the order they were declared
+}
+
+// Node is a rule within a Graph
+type Node struct {
+	Target       string
+	Dependencies []string
+	Commands     []string // Commands with continuation lines joined, not expanded
+}
+
+// NewGraph returns the Graph of the rules within a Builder
+func NewGraph(root Builder) *Graph {
+	g := &Graph{
+		Vars:  make(map[string]string),
+		Phony: make(map[string]bool),
+		Nodes: make(map[string]*Node),
+	}
+
+	if b, ok := root.(*builder); ok {
+		for b.parent != nil {
+			b = b.parent
+		}
+		g.add(b, nil)
+	}
+
+	return g
+}
+
+func (g *Graph) add(b *builder, rule *Node) {
+	switch {
+	case b.IsCommand():
+		g.command(b.command)
+
+	case b.IsRule():
+		deps := strings.Fields(b.line)
+		if b.key == ".PHONY" {
+			for _, d := range deps {
+				g.Phony[d] = true
+			}
+			return
+		}
+		rule = g.node(b.key)
+		rule.Dependencies = appendUnique(rule.Dependencies, deps...)
+
+	case b.IsLine():
+		if rule != nil {
+			l := len(rule.Commands) - 1
+			if l >= 0 && strings.HasSuffix(rule.Commands[l], `\`) {
+				rule.Commands[l] = strings.TrimSuffix(rule.Commands[l], `\`) + b.line
+			} else {
+				rule.Commands = append(rule.Commands, b.line)
+			}
+		}
+	}
+
+	for _, c := range b.children {
+		g.add(c, rule)
+	}
+}
+
+func (g *Graph) node(target string) *Node {
+	n, exists := g.Nodes[target]
+	if !exists {
+		n = &Node{Target: target}
+		g.Nodes[target] = n
+		g.Order = append(g.Order, target)
+	}
+	return n
+}
+
+// command parses variable declarations, other commands like include are ignored
+func (g *Graph) command(s string) {
+	export := strings.HasPrefix(s, "export ")
+	s = strings.TrimPrefix(s, "export ")
+
+	for _, op := range []string{"?=", ":=", "="} {
+		if k, v, ok := strings.Cut(s, op); ok {
+			k = strings.TrimSpace(k)
+			if strings.ContainsAny(k, " \t") {
+				return
+			}
+
+			if _, exists := g.Vars[k]; op != "?=" || !exists {
+				g.Vars[k] = strings.TrimSpace(strings.ReplaceAll(v, "\\\n\t", ""))
+			}
+
+			if export {
+				g.Exports = appendUnique(g.Exports, k)
+			}
+			return
+		}
+	}
+}
+
+func appendUnique(a []string, b ...string) []string {
+	for _, e := range b {
+		found := false
+		for _, f := range a {
+			found = found || e == f
+		}
+		if !found {
+			a = append(a, e)
+		}
+	}
+	return a
+}
+
+// ParseCommand removes the make prefixes from a recipe line.
+// ignoreError is true if the line was prefixed with "-".
+func ParseCommand(line string) (cmd string, ignoreError bool) {
+	for {
+		switch {
+		case strings.HasPrefix(line, "@"), strings.HasPrefix(line, "+"):
+			line = line[1:]
+		case strings.HasPrefix(line, "-"):
+			ignoreError = true
+			line = line[1:]
+		default:
+			return strings.TrimSpace(line), ignoreError
+		}
+	}
+}
+
+// IsPhony returns true if the target is phony
+func (g *Graph) IsPhony(target string) bool {
+	return g.Phony[target]
+}
+
+// IsFile returns true if the target is a file.
+// This is a source file without a rule, or a target which is not phony and has commands to generate it.
+// Rules without commands, like linux_amd64, only group other targets so are treated as phony
+// even if they are not declared as such.
+func (g *Graph) IsFile(target string) bool {
+	if g.IsPhony(target) {
+		return false
+	}
+	n := g.Get(target)
+	return n == nil || len(n.Commands) > 0
+}
+
+// Get returns the Node for a target or nil if there is no rule for it
+func (g *Graph) Get(target string) *Node {
+	return g.Nodes[target]
+}
+
+// Dependencies returns the expanded dependencies of a Node
+func (g *Graph) Dependencies(n *Node) []string {
+	var deps []string
+	for _, d := range n.Dependencies {
+		deps = append(deps, strings.Fields(g.Expand(d))...)
+	}
+	return deps
+}
+
+// Env returns the exported variables as "name=value", expanded
+func (g *Graph) Env() []string {
+	var env []string
+	for _, k := range g.Exports {
+		env = append(env, k+"="+g.Expand(g.Vars[k]))
+	}
+	return env
+}
+
+// Expand variable references in s.
+//
+// This supports $(NAME), ${NAME}, $$ and the $(or a,b) function.
+// Unknown variables expand to "".
+func (g *Graph) Expand(s string) string {
+	return g.expand(s, 0)
+}
+
+func (g *Graph) expand(s string, depth int) string {
+	if depth > 16 || !strings.Contains(s, "$") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '$' || i+1 >= len(s) {
+			sb.WriteByte(c)
+			continue
+		}
+
+		switch open := s[i+1]; open {
+		case '$':
+			sb.WriteByte('$')
+			i++
+
+		case '(', '{':
+			end := matching(s, i+1)
+			if end < 0 {
+				sb.WriteString(s[i:])
+				return sb.String()
+			}
+			sb.WriteString(g.reference(s[i+2:end], depth))
+			i = end
+
+		default:
+			// Single character variable, e.g. $@, which we do not support
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// StripReferences removes the variable references from s, leaving $$ as $.
+// This is the text of a recipe line which is not from a variable.
+func StripReferences(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '$' || i+1 >= len(s) {
+			sb.WriteByte(c)
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			i++
+
+		case '(', '{':
+			end := matching(s, i+1)
+			if end < 0 {
+				sb.WriteString(s[i:])
+				return sb.String()
+			}
+			i = end
+
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// matching returns the index of the bracket closing the one at s[i]
+func matching(s string, i int) int {
+	open, close := s[i], byte(')')
+	if open == '{' {
+		close = '}'
+	}
+
+	nest := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case open:
+			nest++
+		case close:
+			nest--
+			if nest == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func (g *Graph) reference(ref string, depth int) string {
+	if args, ok := strings.CutPrefix(ref, "or "); ok {
+		for _, a := range strings.Split(args, ",") {
+			if v := strings.TrimSpace(g.expand(a, depth+1)); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return g.expand(g.Vars[g.expand(ref, depth+1)], depth+1)
+}
@@ -0,0 +1,167 @@
+package makefile
+
+import (
+	"slices"
+	"testing"
+)
+
+func testGraph() *Graph {
+	b := New()
+	b.SetVar("BUILD", "go run build.go")
+	b.Command("export BUILD_VERSION = \"1.0\"")
+	b.Command("DEST ?= builds")
+	b.Command("DEST ?= other")
+	b.SetVar("TOOLS", "$(DEST)/bin/a $(DEST)/bin/b")
+	b.Include("other.mk")
+	b.Phony("all", "init")
+	b.Rule("init").Line("@mkdir -p $(DEST)")
+	b.Rule("all", "$(TOOLS)")
+	b.Rule("$(DEST)/bin/a", "init", "a.go").
+		Line(`@echo "BUILD a";\`).
+		Line("$(BUILD) -go build a")
+	b.Rule("$(DEST)/bin/a", "common.go")
+	return NewGraph(b)
+}
+
+func TestNewGraph(t *testing.T) {
+	g := testGraph()
+
+	if got := g.Vars["DEST"]; got != "builds" {
+		t.Errorf("DEST = %q, want builds as ?= does not override", got)
+	}
+	if !slices.Equal(g.Exports, []string{"BUILD_VERSION"}) {
+		t.Errorf("Exports = %v", g.Exports)
+	}
+	if !g.IsPhony("all") || !g.IsPhony("init") || g.IsPhony("$(DEST)/bin/a") {
+		t.Errorf("Phony = %v", g.Phony)
+	}
+	if !slices.Equal(g.Order, []string{"init", "all", "$(DEST)/bin/a"}) {
+		t.Errorf("Order = %v", g.Order)
+	}
+
+	n := g.Get("$(DEST)/bin/a")
+	if n == nil {
+		t.Fatal("no rule for $(DEST)/bin/a")
+	}
+	if want := []string{"init", "a.go", "common.go"}; !slices.Equal(n.Dependencies, want) {
+		t.Errorf("Dependencies = %v, want %v", n.Dependencies, want)
+	}
+	if want := []string{`@echo "BUILD a";$(BUILD) -go build a`}; !slices.Equal(n.Commands, want) {
+		t.Errorf("Commands = %q, want %q", n.Commands, want)
+	}
+
+	if want := []string{"builds/bin/a", "builds/bin/b"}; !slices.Equal(g.Dependencies(g.Get("all")), want) {
+		t.Errorf("Dependencies(all) = %v, want %v", g.Dependencies(g.Get("all")), want)
+	}
+	if want := []string{`BUILD_VERSION="1.0"`}; !slices.Equal(g.Env(), want) {
+		t.Errorf("Env() = %v, want %v", g.Env(), want)
+	}
+}
+
+func TestGraph_IsFile(t *testing.T) {
+	b := New()
+	b.Phony("all", "clean")
+	b.Rule("all", "linux_amd64")
+	b.Rule("clean").Line("rm -rf builds")
+	b.Rule("linux_amd64", "tool")
+	b.Rule("tool", "main.go").Line("go build -o tool")
+	g := NewGraph(b)
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{target: "all", want: false},
+		{target: "clean", want: false},
+		{target: "linux_amd64", want: false},
+		{target: "tool", want: true},
+		{target: "main.go", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := g.IsFile(tt.target); got != tt.want {
+				t.Errorf("IsFile(%q) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph_Expand(t *testing.T) {
+	g := &Graph{Vars: map[string]string{
+		"A":    "a",
+		"B":    "$(A)b",
+		"NAME": "A",
+		"E":    "",
+	}}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "plain", want: "plain"},
+		{in: "$(A)", want: "a"},
+		{in: "${A}", want: "a"},
+		{in: "x $(B) y", want: "x ab y"},
+		{in: "$($(NAME))", want: "a"},
+		{in: "$(UNKNOWN)", want: ""},
+		{in: "$$HOME", want: "$HOME"},
+		{in: "$(or $(E),$(A))", want: "a"},
+		{in: "$(or $(E), )", want: ""},
+		{in: "$@", want: "$@"},
+		{in: "$(A", want: "$(A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := g.Expand(tt.in); got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph_Expand_Recursive(t *testing.T) {
+	g := &Graph{Vars: map[string]string{"A": "$(A)x"}}
+	// Must terminate
+	_ = g.Expand("$(A)")
+}
+
+func TestStripReferences(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "$(BUILD) -go build", want: " -go build"},
+		{in: "${BUILD} $(or $(A),b)", want: " "},
+		{in: "rm -f $$HOME", want: "rm -f $HOME"},
+		{in: "a | b", want: "a | b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := StripReferences(tt.in); got != tt.want {
+				t.Errorf("StripReferences(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		in          string
+		want        string
+		ignoreError bool
+	}{
+		{in: "echo a", want: "echo a"},
+		{in: "@echo a", want: "echo a"},
+		{in: "-@rm -f x", want: "rm -f x", ignoreError: true},
+		{in: "@-rm -f x", want: "rm -f x", ignoreError: true},
+		{in: "+make all ", want: "make all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ignoreError := ParseCommand(tt.in)
+			if got != tt.want || ignoreError != tt.ignoreError {
+				t.Errorf("ParseCommand(%q) = %q, %v, want %q, %v", tt.in, got, ignoreError, tt.want, tt.ignoreError)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ type Meta struct {
 	Time          string // Time of build
 	Uid           string // Userid or "N/A" if not available
 	Version       string
+	Tag           string // v* tag at HEAD, blank if HEAD is not tagged
 	ArchTarget    makefile.Builder
 	DistTarget    makefile.Builder
 }
@@ -113,7 +114,63 @@ func (m *Meta) getVersion() error {
 			return err
 		}
 		m.Version = strings.ReplaceAll(s, "-", ".")
+
+		// Note the tag if HEAD is a release
+		if tag, err := runCmd("git", "describe", "--tags", "--exact-match", "--match=v*"); err == nil {
+			m.Tag = tag
+		}
 	}
 
 	return nil
 }
+
+// GoVersion returns the go version declared in go.mod, e.g. "1.24".
+// Returns "" if go.mod cannot be read or has no go line.
+func GoVersion() string {
+	b, err := os.ReadFile("go.mod")
+	if err != nil {
+		return ""
+	}
+
+	for _, s := range strings.Split(string(b), "\n") {
+		a := strings.Fields(s)
+		if len(a) == 2 && a[0] == "go" {
+			return a[1]
+		}
+	}
+	return ""
+}
+
+// ReleaseVersion returns the VERSION environment variable or the most recent v* tag in the git repository.
+// This is the same tag getVersion bases the version on but without the commit count or hash.
+// Returns "" if there is no such tag.
+func ReleaseVersion() string {
+	if v := os.Getenv("VERSION"); v != "" {
+		return v
+	}
+
+	s, err := runCmd("git", "describe", "--tags", "--abbrev=0", "--match=v*")
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// DefaultBranch returns the default branch of the origin remote, e.g. "main".
+// Returns "main" if there is no origin or its HEAD is not known.
+func DefaultBranch() string {
+	s, err := runCmd("git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	if err != nil || !strings.HasPrefix(s, "origin/") {
+		return "main"
+	}
+	return strings.TrimPrefix(s, "origin/")
+}
+
+// IsDirty returns true if the git working tree has uncommitted changes
+func IsDirty() (bool, error) {
+	s, err := runCmd("git", "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return s != "", nil
+}
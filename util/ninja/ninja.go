@@ -0,0 +1,172 @@
+package ninja
+
+import (
+	"fmt"
+	"github.com/peter-mount/go-build/util/makefile"
+	"sort"
+	"strings"
+)
+
+// Pool limits how many of the matching rules ninja will run concurrently
+type Pool struct {
+	Name  string
+	Depth int
+	Match func(n *makefile.Node) bool
+}
+
+// Ninja renders a makefile.Graph as a ninja build file
+type Ninja struct {
+	Graph *makefile.Graph
+	Pools []Pool
+}
+
+// New returns a Ninja for a Graph
+func New(g *makefile.Graph) *Ninja {
+	return &Ninja{Graph: g}
+}
+
+// AddPool adds a pool which rules matching f will run in
+func (n *Ninja) AddPool(name string, depth int, f func(n *makefile.Node) bool) *Ninja {
+	n.Pools = append(n.Pools, Pool{Name: name, Depth: depth, Match: f})
+	return n
+}
+
+// Build returns the ninja build file
+func (n *Ninja) Build() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Generated ninja build file\n")
+	sb.WriteString("ninja_required_version = 1.5\n\n")
+
+	// Exported variables are set in the environment of every command
+	command := "$cmd"
+	if env := n.exports(); env != "" {
+		writeVar(&sb, "", "env", env)
+		sb.WriteString("\n")
+		command = "$env && $cmd"
+	}
+
+	for _, p := range n.Pools {
+		_, _ = fmt.Fprintf(&sb, "pool %s\n  depth = %d\n\n", p.Name, p.Depth)
+	}
+
+	_, _ = fmt.Fprintf(&sb, "rule cmd\n  command = %s\n  description = $desc\n\n", command)
+
+	for _, target := range n.Graph.Order {
+		n.build(&sb, n.Graph.Get(target))
+	}
+
+	if n.Graph.Get("all") != nil {
+		sb.WriteString("default all\n")
+	}
+
+	return sb.String()
+}
+
+func (n *Ninja) build(sb *strings.Builder, node *makefile.Node) {
+	g := n.Graph
+	target := g.Expand(node.Target)
+
+	// Dependencies on phony targets are order-only for real files
+	// otherwise they would be rebuilt every time
+	var deps, orderOnly []string
+	for _, d := range g.Dependencies(node) {
+		if g.Get(d) != nil && !g.IsFile(d) && g.IsFile(target) {
+			orderOnly = append(orderOnly, escapePath(d))
+		} else {
+			deps = append(deps, escapePath(d))
+		}
+	}
+
+	cmd := n.command(node)
+	rule := "cmd"
+	if cmd == "" {
+		rule = "phony"
+	}
+
+	_, _ = fmt.Fprintf(sb, "build %s: %s", escapePath(target), rule)
+	if len(deps) > 0 {
+		sb.WriteString(" " + strings.Join(deps, " "))
+	}
+	if len(orderOnly) > 0 {
+		sb.WriteString(" || " + strings.Join(orderOnly, " "))
+	}
+	sb.WriteString("\n")
+
+	if cmd != "" {
+		writeVar(sb, "  ", "cmd", cmd)
+		writeVar(sb, "  ", "desc", target)
+		for _, p := range n.Pools {
+			if p.Match(node) {
+				writeVar(sb, "  ", "pool", p.Name)
+				break
+			}
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// command returns the recipe of a node as a single shell command
+func (n *Ninja) command(node *makefile.Node) string {
+	type line struct {
+		cmd         string
+		ignoreError bool
+	}
+
+	var lines []line
+	for _, l := range node.Commands {
+		c, ignoreError := makefile.ParseCommand(n.Graph.Expand(l))
+		if c != "" {
+			lines = append(lines, line{cmd: c, ignoreError: ignoreError})
+		}
+	}
+
+	// Like make, run each line in its own shell when there is more than one
+	var cmds []string
+	for _, l := range lines {
+		c := l.cmd
+		if l.ignoreError {
+			c = c + " || true"
+		}
+		if len(lines) > 1 {
+			c = "(" + c + ")"
+		}
+		cmds = append(cmds, c)
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// exports returns the exported variables as a shell export statement
+func (n *Ninja) exports() string {
+	env := n.Graph.Env()
+	if len(env) == 0 {
+		return ""
+	}
+	sort.Strings(env)
+
+	var a []string
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		a = append(a, k+"="+quote(v))
+	}
+	return "export " + strings.Join(a, " ")
+}
+
+// quote a value for the shell so it is passed as is, like make does
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeVar(sb *strings.Builder, indent, name, value string) {
+	_, _ = fmt.Fprintf(sb, "%s%s = %s\n", indent, name, escape(value))
+}
+
+// escape a variable value
+func escape(s string) string {
+	return strings.NewReplacer("$", "$$", "\n", "$\n").Replace(s)
+}
+
+// escapePath escapes a path in a build statement
+func escapePath(s string) string {
+	return strings.NewReplacer("$", "$$", " ", "$ ", ":", "$:").Replace(s)
+}
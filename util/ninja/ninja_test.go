@@ -0,0 +1,38 @@
+package ninja
+
+import (
+	"github.com/peter-mount/go-build/util/makefile"
+	"strings"
+	"testing"
+)
+
+func TestNinja_Build(t *testing.T) {
+	b := makefile.New()
+	b.SetVar("BUILD", "go run build.go")
+	b.Command("export BUILD_VERSION = \"1.0\"")
+	b.Phony("all", "init")
+	b.Rule("init").Line("@mkdir -p builds")
+	b.Rule("all", "builds/bin/tool")
+	b.Rule("builds/bin/tool", "main.go").
+		Line("@$(BUILD) -go build linux amd64 tool").
+		Line("-@rm -f $$HOME/x")
+
+	got := New(makefile.NewGraph(b)).
+		AddPool("go", 2, func(n *makefile.Node) bool { return n.Target == "builds/bin/tool" }).
+		Build()
+
+	for _, want := range []string{
+		`env = export BUILD_VERSION='"1.0"'`,
+		"pool go\n  depth = 2\n",
+		"command = $env && $cmd",
+		"build init: cmd\n  cmd = mkdir -p builds\n",
+		"build all: phony builds/bin/tool\n",
+		"build builds/bin/tool: cmd main.go\n  cmd = (go run build.go -go build linux amd64 tool) && (rm -f $$HOME/x || true)\n",
+		"  pool = go\n",
+		"default all\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
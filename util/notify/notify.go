@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	FormatJSON  = "json"  // Generic JSON payload
+	FormatSlack = "slack" // Slack compatible incoming webhook
+)
+
+// Payload is the build summary sent to a webhook
+type Payload struct {
+	Project   string     `json:"project"`
+	Version   string     `json:"version"`
+	Status    string     `json:"status"`              // success, failure, unstable or aborted
+	Platforms []string   `json:"platforms"`           // Platforms built
+	Failures  []string   `json:"failures,omitempty"`  // Platforms attempted but not built when the build failed
+	Artifacts []Artifact `json:"artifacts,omitempty"` // Manifest of files in dist
+	Duration  float64    `json:"duration,omitempty"`  // Duration of the build in seconds, 0 if unknown
+}
+
+// Artifact is an entry in the artifact manifest
+type Artifact struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// IsSuccess returns true if the build succeeded
+func (p Payload) IsSuccess() bool {
+	return p.Status == "success"
+}
+
+// Summary returns a single line summary of the build
+func (p Payload) Summary() string {
+	s := fmt.Sprintf("%s %s build %s", p.Project, p.Version, p.Status)
+	if p.Duration > 0 {
+		s = s + " in " + (time.Duration(p.Duration) * time.Second).String()
+	}
+	return s
+}
+
+// Body returns the payload formatted for a webhook format
+func (p Payload) Body(format string) ([]byte, error) {
+	switch format {
+	case "", FormatJSON:
+		return json.Marshal(p)
+
+	case FormatSlack:
+		return json.Marshal(p.slack())
+
+	default:
+		return nil, fmt.Errorf("unsupported webhook format %q", format)
+	}
+}
+
+func (p Payload) slack() any {
+	color := "good"
+	if !p.IsSuccess() {
+		color = "danger"
+	}
+
+	fields := []map[string]any{
+		{"title": "Platforms built", "value": fmt.Sprintf("%d", len(p.Platforms)), "short": true},
+		{"title": "Artifacts", "value": fmt.Sprintf("%d", len(p.Artifacts)), "short": true},
+	}
+	if len(p.Failures) > 0 {
+		fields = append(fields, map[string]any{"title": "Not built", "value": strings.Join(p.Failures, " ")})
+	}
+
+	return map[string]any{
+		"text": p.Summary(),
+		"attachments": []map[string]any{
+			{"color": color, "fields": fields},
+		},
+	}
+}
+
+// Send posts the payload to a webhook
+func Send(client *http.Client, url, format string, p Payload) error {
+	body, err := p.Body(format)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
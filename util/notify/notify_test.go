@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testPayload = Payload{
+	Project:   "app",
+	Version:   "v1.0.0",
+	Status:    "failure",
+	Platforms: []string{"linux:amd64:"},
+	Failures:  []string{"linux:arm:7"},
+	Artifacts: []Artifact{{Name: "app.tgz", Size: 3, Sha256: "abc"}},
+	Duration:  90,
+}
+
+func TestPayload_Summary(t *testing.T) {
+	if got := testPayload.Summary(); got != "app v1.0.0 build failure in 1m30s" {
+		t.Errorf("Summary() = %q", got)
+	}
+
+	p := Payload{Project: "app", Version: "v1.0.0", Status: "success"}
+	if got := p.Summary(); got != "app v1.0.0 build success" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
+
+func TestPayload_Body(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			format: FormatJSON,
+			want: `{"project":"app","version":"v1.0.0","status":"failure","platforms":["linux:amd64:"],` +
+				`"failures":["linux:arm:7"],"artifacts":[{"name":"app.tgz","size":3,"sha256":"abc"}],"duration":90}`,
+		},
+		{
+			format: FormatSlack,
+			want: `{"attachments":[{"color":"danger","fields":[` +
+				`{"short":true,"title":"Platforms built","value":"1"},` +
+				`{"short":true,"title":"Artifacts","value":"1"},` +
+				`{"title":"Not built","value":"linux:arm:7"}]}],` +
+				`"text":"app v1.0.0 build failure in 1m30s"}`,
+		},
+		{format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := testPayload.Body(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Body() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Body()\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	if err := Send(srv.Client(), srv.URL+"/hook", FormatJSON, testPayload); err != nil {
+		t.Fatal(err)
+	}
+	if got.Project != "app" || got.Status != "failure" || len(got.Artifacts) != 1 {
+		t.Errorf("received %+v", got)
+	}
+
+	if err := Send(srv.Client(), srv.URL+"/fail", FormatJSON, testPayload); err == nil {
+		t.Error("expected an error from a 500 response")
+	}
+}
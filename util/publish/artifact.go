@@ -0,0 +1,104 @@
+package publish
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChecksumFile is the name of the checksum file written into dist
+const ChecksumFile = "SHA256SUMS"
+
+// Artifact is a file within dist to be published
+type Artifact struct {
+	Name   string // Name of the file within dist
+	Path   string // Path to the file
+	Size   int64  // Size of the file
+	Sha256 string // Hex encoded sha256 of the file
+}
+
+// Artifacts returns the files in the dist directory, sorted by name.
+// Subdirectories are ignored.
+func Artifacts(dist string) ([]Artifact, error) {
+	entries, err := os.ReadDir(dist)
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []Artifact
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		a := Artifact{
+			Name: e.Name(),
+			Path: filepath.Join(dist, e.Name()),
+			Size: info.Size(),
+		}
+
+		a.Sha256, err = Sha256(a.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		artifacts = append(artifacts, a)
+	}
+
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].Name < artifacts[j].Name
+	})
+
+	return artifacts, nil
+}
+
+// Sha256 returns the hex encoded sha256 of a file
+func Sha256(path string) (string, error) {
+	h := sha256.New()
+	if err := util.CopyToWriter(path, h); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// IsSignature returns true if the artifact is a detached signature or checksum file
+func (a Artifact) IsSignature() bool {
+	return a.Name == ChecksumFile ||
+		strings.HasSuffix(a.Name, ".sig") ||
+		strings.HasSuffix(a.Name, ".asc") ||
+		strings.HasSuffix(a.Name, ".sha256")
+}
+
+// WriteChecksums writes SHA256SUMS into dist for every artifact that is not itself a signature.
+// It returns the artifacts in dist after the file has been written.
+func WriteChecksums(dist string) ([]Artifact, error) {
+	artifacts, err := Artifacts(dist)
+	if err != nil {
+		return nil, err
+	}
+
+	var a []string
+	for _, e := range artifacts {
+		if !e.IsSignature() {
+			a = append(a, fmt.Sprintf("%s  %s\n", e.Sha256, e.Name))
+		}
+	}
+
+	fileName := filepath.Join(dist, ChecksumFile)
+	util.Label("CHECKSUM", "%s", fileName)
+	if err := os.WriteFile(fileName, []byte(strings.Join(a, "")), 0644); err != nil {
+		return nil, err
+	}
+
+	return Artifacts(dist)
+}
@@ -0,0 +1,222 @@
+package publish
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// GitHub publishes releases to the GitHub releases api.
+// Gitea & Forgejo provide a compatible api so this works with them too,
+// in which case BaseURL would be something like "https://gitea.example.com/api/v1".
+type GitHub struct {
+	BaseURL    string // Base url of the api, e.g. https://api.github.com
+	Token      string // Access token
+	Repository string // Repository as owner/name
+	Client     *http.Client
+}
+
+// GitHubRelease is the subset of the release api we use
+type GitHubRelease struct {
+	Id         int64         `json:"id,omitempty"`
+	TagName    string        `json:"tag_name"`
+	Name       string        `json:"name"`
+	Body       string        `json:"body"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	UploadURL  string        `json:"upload_url,omitempty"`
+	Assets     []GitHubAsset `json:"assets,omitempty"`
+}
+
+type GitHubAsset struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewGitHub returns a GitHub configured from the environment.
+//
+// GITHUB_API_URL is the api base url, defaulting to https://api.github.com,
+// GITHUB_TOKEN the access token and GITHUB_REPOSITORY the owner/name of the repository.
+// If GITHUB_REPOSITORY is not set then repository is used instead.
+func NewGitHub(repository string) (*GitHub, error) {
+	g := &GitHub{
+		BaseURL:    strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/"),
+		Token:      os.Getenv("GITHUB_TOKEN"),
+		Repository: os.Getenv("GITHUB_REPOSITORY"),
+		Client:     &http.Client{Timeout: 10 * time.Minute},
+	}
+
+	if g.BaseURL == "" {
+		g.BaseURL = "https://api.github.com"
+	}
+
+	if g.Repository == "" {
+		g.Repository = repository
+	}
+
+	switch {
+	case g.Token == "":
+		return nil, errors.New("GITHUB_TOKEN is not set")
+	case g.Repository == "":
+		return nil, errors.New("GITHUB_REPOSITORY is not set")
+	}
+
+	return g, nil
+}
+
+// RepositoryFromModule returns owner/name from a module path like github.com/owner/name/v2
+func RepositoryFromModule(module string) string {
+	a := strings.Split(module, "/")
+	if len(a) < 3 {
+		return ""
+	}
+	return a[1] + "/" + a[2]
+}
+
+func (g *GitHub) url(f string, a ...any) string {
+	return g.BaseURL + "/repos/" + g.Repository + fmt.Sprintf(f, a...)
+}
+
+// do makes a request with a body of size bytes.
+// The size is always sent as GitHub's uploads server rejects chunked requests.
+func (g *GitHub) do(method, url, contentType string, body io.Reader, size int64, result any) (int, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return 0, err
+	}
+
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "token "+g.Token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s %s: %s %s", method, url, resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	if result != nil && len(b) > 0 {
+		err = json.Unmarshal(b, result)
+	}
+	return resp.StatusCode, err
+}
+
+func (g *GitHub) doJSON(method, url string, body, result any) (int, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	return g.do(method, url, "application/json", bytes.NewReader(b), int64(len(b)), result)
+}
+
+// Publish creates or updates the release and uploads the artifacts to it.
+// Existing assets with the same name as an artifact are replaced.
+func (g *GitHub) Publish(release GitHubRelease, artifacts []Artifact) error {
+	existing := &GitHubRelease{}
+	status, err := g.do(http.MethodGet, g.url("/releases/tags/%s", url.PathEscape(release.TagName)), "", nil, 0, existing)
+
+	switch {
+	case status == http.StatusNotFound:
+		util.Label("RELEASE", "create %s", release.TagName)
+		existing = &GitHubRelease{}
+		_, err = g.doJSON(http.MethodPost, g.url("/releases"), release, existing)
+
+	case err == nil:
+		util.Label("RELEASE", "update %s", release.TagName)
+		id, assets := existing.Id, existing.Assets
+		existing = &GitHubRelease{}
+		_, err = g.doJSON(http.MethodPatch, g.url("/releases/%d", id), release, existing)
+		if len(existing.Assets) == 0 {
+			existing.Assets = assets
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, a := range artifacts {
+		if err := g.upload(existing, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GitHub) upload(release *GitHubRelease, a Artifact) error {
+	// GitHub returns a uri template pointing to its uploads server.
+	// Gitea returns a plain url to its assets endpoint which expects a multipart form.
+	gitea := !strings.Contains(release.UploadURL, "{")
+
+	for _, e := range release.Assets {
+		if e.Name == a.Name {
+			u := g.url("/releases/assets/%d", e.Id)
+			if gitea {
+				u = g.url("/releases/%d/assets/%d", release.Id, e.Id)
+			}
+			if _, err := g.do(http.MethodDelete, u, "", nil, 0, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	util.Label("UPLOAD", "%s", a.Name)
+
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	uploadURL := release.UploadURL
+	if i := strings.Index(uploadURL, "{"); i >= 0 {
+		uploadURL = uploadURL[:i]
+	}
+	if uploadURL == "" {
+		uploadURL = g.url("/releases/%d/assets", release.Id)
+	}
+	uploadURL = uploadURL + "?name=" + url.QueryEscape(a.Name)
+
+	if !gitea {
+		_, err = g.do(http.MethodPost, uploadURL, "application/octet-stream", f, a.Size, nil)
+		return err
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	w, err := mw.CreateFormFile("attachment", a.Name)
+	if err == nil {
+		_, err = io.Copy(w, f)
+	}
+	if err == nil {
+		err = mw.Close()
+	}
+	if err == nil {
+		_, err = g.do(http.MethodPost, uploadURL, mw.FormDataContentType(), &buf, int64(buf.Len()), nil)
+	}
+	return err
+}
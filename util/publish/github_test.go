@@ -0,0 +1,169 @@
+package publish
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testGitHub is an in-memory stand in for the GitHub releases api
+type testGitHub struct {
+	mutex    sync.Mutex
+	url      string
+	release  *GitHubRelease
+	requests []string          // method & path of each request
+	uploads  map[string]string // Uploaded assets by name
+}
+
+func (s *testGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/name/releases/tags/v1.0.0":
+		if s.release == nil {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.release)
+
+	case (r.Method == http.MethodPost && r.URL.Path == "/repos/owner/name/releases") ||
+		(r.Method == http.MethodPatch && r.URL.Path == "/repos/owner/name/releases/1"):
+		release := &GitHubRelease{}
+		if err := json.NewDecoder(r.Body).Decode(release); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		release.Id = 1
+		release.UploadURL = s.url + "/uploads/repos/owner/name/releases/1/assets{?name,label}"
+		s.release = release
+		_ = json.NewEncoder(w).Encode(release)
+
+	case r.Method == http.MethodDelete && r.URL.Path == "/repos/owner/name/releases/assets/10":
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/uploads/repos/owner/name/releases/1/assets":
+		// The uploads server rejects chunked requests
+		if r.ContentLength < 0 || len(r.TransferEncoding) > 0 {
+			http.Error(w, "length required", http.StatusLengthRequired)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		s.uploads[r.URL.Query().Get("name")] = string(b)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprint(w, `{"id":11}`)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestGitHub(t *testing.T) (*testGitHub, *GitHub) {
+	s := &testGitHub{uploads: make(map[string]string)}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	s.url = srv.URL
+
+	return s, &GitHub{
+		BaseURL:    srv.URL,
+		Token:      "secret",
+		Repository: "owner/name",
+		Client:     srv.Client(),
+	}
+}
+
+func testArtifacts(t *testing.T, files map[string]string) []Artifact {
+	dir := t.TempDir()
+	for n, c := range files {
+		if err := os.WriteFile(filepath.Join(dir, n), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	artifacts, err := Artifacts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return artifacts
+}
+
+func TestGitHub_Publish_Create(t *testing.T) {
+	s, g := newTestGitHub(t)
+
+	err := g.Publish(
+		GitHubRelease{TagName: "v1.0.0", Name: "v1.0.0", Body: "notes"},
+		testArtifacts(t, map[string]string{"a.tgz": "aaa", "empty.txt": ""}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.release == nil || s.release.Body != "notes" {
+		t.Errorf("release %+v", s.release)
+	}
+	if s.uploads["a.tgz"] != "aaa" {
+		t.Errorf("uploads %q", s.uploads)
+	}
+	if _, exists := s.uploads["empty.txt"]; !exists {
+		t.Errorf("empty.txt not uploaded")
+	}
+	if slices.Contains(s.requests, "PATCH /repos/owner/name/releases/1") {
+		t.Errorf("new release updated %q", s.requests)
+	}
+}
+
+func TestGitHub_Publish_Update(t *testing.T) {
+	s, g := newTestGitHub(t)
+	s.release = &GitHubRelease{
+		Id:        1,
+		TagName:   "v1.0.0",
+		UploadURL: s.url + "/uploads/repos/owner/name/releases/1/assets{?name,label}",
+		Assets:    []GitHubAsset{{Id: 10, Name: "a.tgz"}},
+	}
+
+	err := g.Publish(
+		GitHubRelease{TagName: "v1.0.0", Name: "v1.0.0", Body: "updated"},
+		testArtifacts(t, map[string]string{"a.tgz": "new"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /repos/owner/name/releases/tags/v1.0.0",
+		"PATCH /repos/owner/name/releases/1",
+		"DELETE /repos/owner/name/releases/assets/10",
+		"POST /uploads/repos/owner/name/releases/1/assets",
+	}
+	if !slices.Equal(s.requests, want) {
+		t.Errorf("requests\n%s\nwant\n%s", strings.Join(s.requests, "\n"), strings.Join(want, "\n"))
+	}
+	if s.release.Body != "updated" || s.uploads["a.tgz"] != "new" {
+		t.Errorf("release %+v uploads %q", s.release, s.uploads)
+	}
+}
+
+func TestGitHub_Publish_Error(t *testing.T) {
+	_, g := newTestGitHub(t)
+	g.Token = "wrong"
+
+	err := g.Publish(GitHubRelease{TagName: "v1.0.0"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Publish() = %v, want 401", err)
+	}
+}
@@ -0,0 +1,120 @@
+package publish
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// HTTP publishes artifacts with HTTP PUT, optionally creating WebDAV collections.
+//
+// Alongside each artifact a ".sha256" file is written containing its hash.
+// This is used to detect if an artifact already exists.
+type HTTP struct {
+	URL      string // Base url
+	WebDAV   bool   // true to create parent collections with MKCOL
+	Username string // Optional username for basic auth
+	Password string // Optional password for basic auth
+	Client   *http.Client
+}
+
+func NewHTTP(url string) *HTTP {
+	return &HTTP{
+		URL:    strings.TrimSuffix(url, "/"),
+		Client: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+func (h *HTTP) url(path string) string {
+	return h.URL + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (h *HTTP) do(method, path string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, h.url(path), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if h.Username != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	return h.Client.Do(req)
+}
+
+func (h *HTTP) Exists(path, sha256 string) (bool, error) {
+	resp, err := h.do(http.MethodGet, path+".sha256", nil, 0)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	f := strings.Fields(string(b))
+	return len(f) > 0 && f[0] == sha256, nil
+}
+
+func (h *HTTP) Put(path string, a Artifact) error {
+	if h.WebDAV {
+		if err := h.mkcol(path); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := h.put(path, f, a.Size); err != nil {
+		return err
+	}
+
+	sum := []byte(a.Sha256 + "  " + a.Name + "\n")
+	return h.put(path+".sha256", bytes.NewReader(sum), int64(len(sum)))
+}
+
+func (h *HTTP) put(path string, body io.Reader, size int64) error {
+	resp, err := h.do(http.MethodPut, path, body, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PUT %s: %s", h.url(path), resp.Status)
+	}
+	return nil
+}
+
+// mkcol creates each parent collection of path.
+// Existing collections return 405 Method Not Allowed which is ignored.
+func (h *HTTP) mkcol(path string) error {
+	a := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(a); i++ {
+		resp, err := h.do("MKCOL", strings.Join(a[:i], "/")+"/", nil, 0)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("MKCOL %s: %s", strings.Join(a[:i], "/"), resp.Status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package publish
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/peter-mount/go-build/util"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Publisher is a backend artifacts can be uploaded to
+type Publisher interface {
+	// Exists returns true if path already exists with the same sha256 hash
+	Exists(path, sha256 string) (bool, error)
+	// Put uploads an Artifact to path
+	Put(path string, a Artifact) error
+}
+
+// PathData is passed to the path template for each artifact
+type PathData struct {
+	PackageName   string // Package name, from the module in go.mod
+	PackagePrefix string // Module in go.mod
+	Version       string // Version being published
+	File          string // Name of the artifact
+}
+
+// DefaultPath is the path template used if none is configured
+const DefaultPath = "{{.PackageName}}/{{.Version}}/{{.File}}"
+
+// Uploader uploads artifacts to a Publisher in parallel
+type Uploader struct {
+	Publisher Publisher
+	Path      *template.Template // Template to generate the path of each artifact
+	Parallel  int                // Number of concurrent uploads
+	Retries   int                // Number of times to retry a failed upload
+	Backoff   time.Duration      // Delay before the first retry, doubling for each one after
+}
+
+// NewUploader creates an Uploader using the path template
+func NewUploader(p Publisher, path string, parallel, retries int) (*Uploader, error) {
+	if path == "" {
+		path = DefaultPath
+	}
+
+	t, err := template.New("path").Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	return &Uploader{
+		Publisher: p,
+		Path:      t,
+		Parallel:  parallel,
+		Retries:   retries,
+		Backoff:   time.Second,
+	}, nil
+}
+
+// Upload the artifacts, skipping any that already exist with the same hash.
+// All artifacts are attempted, the first error, if any, is returned.
+func (u *Uploader) Upload(data PathData, artifacts []Artifact) error {
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+
+	ch := make(chan Artifact)
+	for i := 0; i < u.Parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range ch {
+				if err := u.upload(data, a); err != nil {
+					mutex.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, a := range artifacts {
+		ch <- a
+	}
+	close(ch)
+	wg.Wait()
+
+	return firstErr
+}
+
+func (u *Uploader) upload(data PathData, a Artifact) error {
+	data.File = a.Name
+
+	var buf bytes.Buffer
+	if err := u.Path.Execute(&buf, data); err != nil {
+		return err
+	}
+	path := buf.String()
+
+	var err error
+	for attempt := 0; attempt <= u.Retries; attempt++ {
+		if attempt > 0 {
+			util.Label("RETRY", "%s %d/%d: %v", path, attempt, u.Retries, err)
+			time.Sleep(u.Backoff << (attempt - 1))
+		}
+
+		var exists bool
+		exists, err = u.Publisher.Exists(path, a.Sha256)
+		if err == nil && exists {
+			util.Label("SKIP", "%s", path)
+			return nil
+		}
+
+		if err == nil {
+			util.Label("UPLOAD", "%s", path)
+			err = u.Publisher.Put(path, a)
+		}
+
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("upload %s: %w", path, err)
+}
@@ -0,0 +1,107 @@
+package publish
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testPublisher is an in-memory Publisher which fails the first failures calls to Put for each path
+type testPublisher struct {
+	mutex    sync.Mutex
+	hashes   map[string]string
+	failures int
+	attempts map[string]int
+	puts     []string
+}
+
+func newTestPublisher(failures int) *testPublisher {
+	return &testPublisher{
+		hashes:   make(map[string]string),
+		failures: failures,
+		attempts: make(map[string]int),
+	}
+}
+
+func (p *testPublisher) Exists(path, sha256 string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.hashes[path] == sha256, nil
+}
+
+func (p *testPublisher) Put(path string, a Artifact) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.attempts[path]++
+	if p.attempts[path] <= p.failures {
+		return errors.New("unavailable")
+	}
+	p.hashes[path] = a.Sha256
+	p.puts = append(p.puts, path)
+	sort.Strings(p.puts)
+	return nil
+}
+
+func newTestUploader(t *testing.T, p Publisher, retries int) *Uploader {
+	u, err := NewUploader(p, "{{.PackageName}}/{{.Version}}/{{.File}}", 2, retries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Backoff = 0
+	return u
+}
+
+var testPathData = PathData{PackageName: "app", Version: "v1.0.0"}
+
+func TestUploader_Upload_Skip(t *testing.T) {
+	artifacts := []Artifact{
+		{Name: "a.tgz", Sha256: "aaa"},
+		{Name: "b.tgz", Sha256: "bbb"},
+		{Name: "c.tgz", Sha256: "ccc"},
+	}
+
+	p := newTestPublisher(0)
+	p.hashes["app/v1.0.0/a.tgz"] = "aaa" // Same so skipped
+	p.hashes["app/v1.0.0/b.tgz"] = "old" // Changed so uploaded again
+
+	if err := newTestUploader(t, p, 0).Upload(testPathData, artifacts); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(p.puts, ","); got != "app/v1.0.0/b.tgz,app/v1.0.0/c.tgz" {
+		t.Errorf("puts %s", got)
+	}
+}
+
+func TestUploader_Upload_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		retries  int
+		wantErr  bool
+		attempts int
+	}{
+		{name: "no retries", failures: 0, retries: 3, attempts: 1},
+		{name: "retried", failures: 2, retries: 3, attempts: 3},
+		{name: "too many failures", failures: 4, retries: 3, wantErr: true, attempts: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPublisher(tt.failures)
+
+			err := newTestUploader(t, p, tt.retries).Upload(testPathData, []Artifact{{Name: "a.tgz", Sha256: "aaa"}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Upload() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "app/v1.0.0/a.tgz") {
+				t.Errorf("Upload() = %v, want the path", err)
+			}
+			if got := p.attempts["app/v1.0.0/a.tgz"]; got != tt.attempts {
+				t.Errorf("attempts %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
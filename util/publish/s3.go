@@ -0,0 +1,231 @@
+package publish
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyHash is the sha256 of an empty payload
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3 publishes artifacts to an S3 compatible object store using AWS Signature Version 4.
+//
+// Each object is stored with the metadata "x-amz-meta-sha256" containing its hash.
+// This is used to detect if an artifact already exists.
+type S3 struct {
+	Endpoint     string // Endpoint url, e.g. https://s3.eu-west-2.amazonaws.com
+	Region       string // Region, e.g. eu-west-2
+	Bucket       string // Bucket name
+	AccessKey    string // Access key
+	SecretKey    string // Secret key
+	SessionToken string // Optional session token
+	PathStyle    bool   // true for path style urls, e.g. MinIO, false for virtual host style
+	Client       *http.Client
+}
+
+func NewS3(endpoint, region, bucket string) *S3 {
+	return &S3{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Region:   region,
+		Bucket:   bucket,
+		Client:   &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+func (s *S3) url(key string) (*url.URL, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var a []string
+	for _, p := range strings.Split(strings.TrimPrefix(key, "/"), "/") {
+		a = append(a, uriEncode(p))
+	}
+	path := strings.Join(a, "/")
+
+	if s.PathStyle {
+		u.RawPath = "/" + uriEncode(s.Bucket) + "/" + path
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.RawPath = "/" + path
+	}
+
+	u.Path, err = url.PathUnescape(u.RawPath)
+	return u, err
+}
+
+func (s *S3) do(method, key, payloadHash string, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	u, err := s.url(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	s.sign(req, payloadHash, time.Now())
+	return s.Client.Do(req)
+}
+
+func (s *S3) Exists(path, sha256 string) (bool, error) {
+	resp, err := s.do(http.MethodHead, path, emptyHash, nil, 0, nil)
+	if err != nil {
+		return false, err
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("HEAD %s: %s", path, resp.Status)
+	default:
+		return resp.Header.Get("x-amz-meta-sha256") == sha256, nil
+	}
+}
+
+func (s *S3) Put(path string, a Artifact) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	resp, err := s.do(http.MethodPut, path, a.Sha256, f, a.Size, map[string]string{
+		"Content-Type":      "application/octet-stream",
+		"x-amz-meta-sha256": a.Sha256,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("PUT %s: %s %s", path, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// uriEncode percent encodes everything except the RFC 3986 unreserved characters,
+// as required for the canonical request of Signature Version 4
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		default:
+			_, _ = fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// canonicalQuery returns the query parameters sorted by name then value, each encoded with uriEncode
+func canonicalQuery(q url.Values) string {
+	var a [][2]string
+	for k, v := range q {
+		for _, e := range v {
+			a = append(a, [2]string{uriEncode(k), uriEncode(e)})
+		}
+	}
+	sort.Slice(a, func(i, j int) bool {
+		return a[i][0] < a[j][0] || (a[i][0] == a[j][0] && a[i][1] < a[j][1])
+	})
+
+	var sb strings.Builder
+	for i, p := range a {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(p[0] + "=" + p[1])
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sign a request using AWS Signature Version 4
+func (s *S3) sign(req *http.Request, payloadHash string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+
+	// Sign host and every x-amz-* header
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+
+	var keys []string
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range keys {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(keys, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
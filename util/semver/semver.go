@@ -0,0 +1,111 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version as used in v* tags
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Pre   string // Pre-release identifier, e.g. "rc.1", blank for a release
+}
+
+// Parse a version like "v1.2.3" or "v1.2.3-rc.1". The leading "v" is optional.
+// Build metadata after a "+" is ignored.
+func Parse(s string) (Version, error) {
+	var v Version
+
+	src := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, v.Pre, _ = strings.Cut(s, "-")
+
+	a := strings.Split(s, ".")
+	if len(a) != 3 {
+		return v, fmt.Errorf("invalid semantic version %q", src)
+	}
+
+	for i, p := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(a[i])
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid semantic version %q", src)
+		}
+		*p = n
+	}
+
+	return v, nil
+}
+
+// String returns the version in tag form, e.g. "v1.2.3"
+func (v Version) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s = s + "-" + v.Pre
+	}
+	return s
+}
+
+// IsPreRelease returns true if this is a pre-release version
+func (v Version) IsPreRelease() bool {
+	return v.Pre != ""
+}
+
+// Bump returns the next version.
+//
+// kind is one of "major", "minor", "patch" or "pre".
+// Bumping a pre-release to the release it precedes drops the pre-release,
+// e.g. patch on v1.2.4-rc.2 gives v1.2.4.
+// "pre" increments the pre-release number, or starts rc.1 for the next patch version.
+func (v Version) Bump(kind string) (Version, error) {
+	n := v
+	n.Pre = ""
+
+	switch kind {
+	case "major":
+		if !v.IsPreRelease() || v.Minor != 0 || v.Patch != 0 {
+			n.Major++
+			n.Minor = 0
+			n.Patch = 0
+		}
+
+	case "minor":
+		if !v.IsPreRelease() || v.Patch != 0 {
+			n.Minor++
+			n.Patch = 0
+		}
+
+	case "patch":
+		if !v.IsPreRelease() {
+			n.Patch++
+		}
+
+	case "pre":
+		if v.IsPreRelease() {
+			n.Pre = bumpPre(v.Pre)
+		} else {
+			n.Patch++
+			n.Pre = "rc.1"
+		}
+
+	default:
+		return v, fmt.Errorf("unknown version bump %q, expected major, minor, patch or pre", kind)
+	}
+
+	return n, nil
+}
+
+// bumpPre increments the last numeric identifier in a pre-release,
+// appending ".1" if there is none
+func bumpPre(pre string) string {
+	a := strings.Split(pre, ".")
+	l := len(a) - 1
+	if n, err := strconv.Atoi(a[l]); err == nil {
+		a[l] = strconv.Itoa(n + 1)
+		return strings.Join(a, ".")
+	}
+	return pre + ".1"
+}
@@ -0,0 +1,46 @@
+package semver
+
+import "testing"
+
+func TestVersion_Bump(t *testing.T) {
+	tests := []struct {
+		version string
+		kind    string
+		want    string
+	}{
+		{version: "v1.2.3", kind: "major", want: "v2.0.0"},
+		{version: "v1.2.3", kind: "minor", want: "v1.3.0"},
+		{version: "v1.2.3", kind: "patch", want: "v1.2.4"},
+		{version: "v1.2.3", kind: "pre", want: "v1.2.4-rc.1"},
+		{version: "v1.2.4-rc.1", kind: "pre", want: "v1.2.4-rc.2"},
+		{version: "v1.2.4-beta", kind: "pre", want: "v1.2.4-beta.1"},
+		{version: "v1.2.4-rc.2", kind: "patch", want: "v1.2.4"},
+		{version: "v1.2.4-rc.2", kind: "minor", want: "v1.3.0"},
+		{version: "v1.3.0-rc.2", kind: "minor", want: "v1.3.0"},
+		{version: "v2.0.0-rc.2", kind: "major", want: "v2.0.0"},
+		{version: "0.1.0", kind: "patch", want: "v0.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.kind, func(t *testing.T) {
+			v, err := Parse(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := v.Bump(tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Bump() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{"", "v1", "v1.2", "v1.x.3", "latest"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) expected error", s)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package taskfile
+
+import (
+	"fmt"
+	"github.com/peter-mount/go-build/util/makefile"
+	"sort"
+	"strings"
+)
+
+// Taskfile renders a makefile.Graph as a Taskfile.yml for https://taskfile.dev
+type Taskfile struct {
+	Graph *makefile.Graph
+}
+
+// New returns a Taskfile for a Graph
+func New(g *makefile.Graph) *Taskfile {
+	return &Taskfile{Graph: g}
+}
+
+// Build returns the Taskfile.yml
+func (t *Taskfile) Build() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Generated Taskfile\n")
+	sb.WriteString("version: '3'\n\n")
+
+	// Like make, compare timestamps and only run each task once per invocation
+	sb.WriteString("method: timestamp\n")
+	sb.WriteString("run: once\n")
+	sb.WriteString("silent: true\n\n")
+
+	if env := t.Graph.Env(); len(env) > 0 {
+		sort.Strings(env)
+		sb.WriteString("env:\n")
+		for _, e := range env {
+			k, v, _ := strings.Cut(e, "=")
+			_, _ = fmt.Fprintf(&sb, "  %s: %s\n", k, quote(v))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("tasks:\n")
+	if t.Graph.Get("all") != nil && t.Graph.Get("default") == nil {
+		sb.WriteString("  default:\n    deps: [all]\n\n")
+	}
+
+	for _, target := range t.Graph.Order {
+		t.task(&sb, t.Graph.Get(target))
+	}
+
+	return sb.String()
+}
+
+func (t *Taskfile) task(sb *strings.Builder, node *makefile.Node) {
+	g := t.Graph
+	target := g.Expand(node.Target)
+
+	// Dependencies with rules are tasks, files are also sources
+	var deps, sources []string
+	for _, d := range g.Dependencies(node) {
+		if g.Get(d) != nil {
+			deps = append(deps, quote(d))
+		}
+		if g.IsFile(d) {
+			sources = append(sources, quote(d))
+		}
+	}
+
+	_, _ = fmt.Fprintf(sb, "  %s:\n", quote(target))
+	writeList(sb, "deps", deps)
+	writeList(sb, "sources", sources)
+	if g.IsFile(target) {
+		writeList(sb, "generates", []string{quote(target)})
+
+		// Without sources Task would always run it, so like make just check it exists
+		if len(sources) == 0 {
+			writeList(sb, "status", []string{quote("test -e " + shellQuote(target))})
+		}
+	}
+
+	var cmds []string
+	for _, l := range node.Commands {
+		c, ignoreError := makefile.ParseCommand(g.Expand(l))
+		switch {
+		case c == "":
+		case ignoreError:
+			cmds = append(cmds, "- cmd: "+quote(escape(c))+"\n        ignore_error: true")
+		default:
+			cmds = append(cmds, "- "+quote(escape(c)))
+		}
+	}
+	if len(cmds) > 0 {
+		sb.WriteString("    cmds:\n")
+		for _, c := range cmds {
+			_, _ = fmt.Fprintf(sb, "      %s\n", c)
+		}
+	}
+	sb.WriteString("\n")
+}
+
+func writeList(sb *strings.Builder, name string, values []string) {
+	if len(values) > 0 {
+		_, _ = fmt.Fprintf(sb, "    %s:\n", name)
+		for _, v := range values {
+			_, _ = fmt.Fprintf(sb, "      - %s\n", v)
+		}
+	}
+}
+
+// escape templates in a command, as Task treats {{ }} as a go template
+func escape(s string) string {
+	return strings.ReplaceAll(s, "{{", `{{"{{"}}`)
+}
+
+// shellQuote quotes a value for the shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quote a yaml string
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,76 @@
+package taskfile
+
+import (
+	"github.com/peter-mount/go-build/util/makefile"
+	"testing"
+)
+
+func TestTaskfile_Build(t *testing.T) {
+	b := makefile.New()
+	b.SetVar("BUILD", "go run build.go")
+	b.Command("export BUILD_VERSION = \"1.0\"")
+	b.Phony("all", "init", "clean")
+	b.Rule("init").Line("@mkdir -p builds")
+	b.Rule("clean").Line("-@rm -rf builds")
+	b.Rule("all", "linux_amd64")
+	b.Rule("linux_amd64", "init", "builds/bin/tool")
+	b.Rule("builds/bin/tool", "main.go").
+		Line("@$(BUILD) -go build linux amd64 tool")
+	b.Rule("builds/bin/config").
+		Line("@echo '{{ x }}' >builds/bin/config")
+
+	want := `# Generated Taskfile
+version: '3'
+
+method: timestamp
+run: once
+silent: true
+
+env:
+  BUILD_VERSION: '"1.0"'
+
+tasks:
+  default:
+    deps: [all]
+
+  'init':
+    cmds:
+      - 'mkdir -p builds'
+
+  'clean':
+    cmds:
+      - cmd: 'rm -rf builds'
+        ignore_error: true
+
+  'all':
+    deps:
+      - 'linux_amd64'
+
+  'linux_amd64':
+    deps:
+      - 'init'
+      - 'builds/bin/tool'
+    sources:
+      - 'builds/bin/tool'
+
+  'builds/bin/tool':
+    sources:
+      - 'main.go'
+    generates:
+      - 'builds/bin/tool'
+    cmds:
+      - 'go run build.go -go build linux amd64 tool'
+
+  'builds/bin/config':
+    generates:
+      - 'builds/bin/config'
+    status:
+      - 'test -e ''builds/bin/config'''
+    cmds:
+      - 'echo ''{{"{{"}} x }}'' >builds/bin/config'
+
+`
+	if got := New(makefile.NewGraph(b)).Build(); got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+}
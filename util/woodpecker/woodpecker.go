@@ -0,0 +1,79 @@
+package woodpecker
+
+import (
+	"gopkg.in/yaml.v2"
+)
+
+// Pipeline is a Woodpecker CI pipeline, the content of .woodpecker.yml
+type Pipeline struct {
+	When  []When  `yaml:"when,omitempty"`
+	Steps []*Step `yaml:"steps"`
+}
+
+type When struct {
+	Event []string `yaml:"event,omitempty"`
+	Ref   string   `yaml:"ref,omitempty"`
+}
+
+type Step struct {
+	Name        string         `yaml:"name"`
+	Image       string         `yaml:"image"`
+	Environment map[string]any `yaml:"environment,omitempty"`
+	Volumes     []string       `yaml:"volumes,omitempty"`
+	Commands    []string       `yaml:"commands"`
+	DependsOn   []string       `yaml:"depends_on,omitempty"`
+	When        []When         `yaml:"when,omitempty"`
+}
+
+// Secret is an environment value taken from a secret
+type Secret struct {
+	FromSecret string `yaml:"from_secret"`
+}
+
+// TagEvent limits a step to v* tags
+var TagEvent = When{Event: []string{"tag"}, Ref: "refs/tags/v*"}
+
+// New returns a pipeline run on pushes, tags, pull requests & manually
+func New() *Pipeline {
+	return &Pipeline{
+		When: []When{{Event: []string{"push", "tag", "pull_request", "manual"}}},
+	}
+}
+
+// AddStep adds a step to the pipeline
+func (p *Pipeline) AddStep(step *Step) *Pipeline {
+	p.Steps = append(p.Steps, step)
+	return p
+}
+
+// StepNames returns the name of each step
+func (p *Pipeline) StepNames() []string {
+	var names []string
+	for _, s := range p.Steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// Build returns the pipeline as yaml
+func (p *Pipeline) Build() (string, error) {
+	b, err := yaml.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return "# Generated Woodpecker pipeline\n" + string(b), nil
+}
+
+// NewStep returns a step running commands within an image
+func NewStep(name, image string, commands ...string) *Step {
+	return &Step{Name: name, Image: image, Commands: commands}
+}
+
+// Env sets an environment variable
+func (s *Step) Env(name string, value any) *Step {
+	if s.Environment == nil {
+		s.Environment = make(map[string]any)
+	}
+	s.Environment[name] = value
+	return s
+}
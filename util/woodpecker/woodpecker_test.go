@@ -0,0 +1,64 @@
+package woodpecker
+
+import "testing"
+
+func TestPipeline_Build(t *testing.T) {
+	p := New().
+		AddStep(NewStep("init", "golang:1.22", "make clean init")).
+		AddStep(&Step{
+			Name:      "linux",
+			Image:     "golang:1.22",
+			Volumes:   []string{"go-mod-cache:/go/pkg/mod"},
+			Commands:  []string{"make -f Makefile.gen linux"},
+			DependsOn: []string{"init"},
+		})
+
+	publish := NewStep("publish", "golang:1.22", "make -f Makefile.gen publish").
+		Env("GITHUB_TOKEN", Secret{FromSecret: "github_token"})
+	publish.When = []When{TagEvent}
+	p.AddStep(publish)
+
+	want := `# Generated Woodpecker pipeline
+when:
+- event:
+  - push
+  - tag
+  - pull_request
+  - manual
+steps:
+- name: init
+  image: golang:1.22
+  commands:
+  - make clean init
+- name: linux
+  image: golang:1.22
+  volumes:
+  - go-mod-cache:/go/pkg/mod
+  commands:
+  - make -f Makefile.gen linux
+  depends_on:
+  - init
+- name: publish
+  image: golang:1.22
+  environment:
+    GITHUB_TOKEN:
+      from_secret: github_token
+  commands:
+  - make -f Makefile.gen publish
+  when:
+  - event:
+    - tag
+    ref: refs/tags/v*
+`
+	got, err := p.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+
+	if names := p.StepNames(); len(names) != 3 || names[2] != "publish" {
+		t.Errorf("StepNames() = %v", names)
+	}
+}
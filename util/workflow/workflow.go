@@ -0,0 +1,128 @@
+package workflow
+
+import (
+	"gopkg.in/yaml.v2"
+)
+
+// Workflow is a GitHub Actions workflow.
+// Gitea and Forgejo Actions use the same format.
+type Workflow struct {
+	Name string        `yaml:"name"`
+	On   On            `yaml:"on"`
+	Jobs yaml.MapSlice `yaml:"jobs"` // Jobs in the order they were added
+}
+
+// On are the events which trigger the workflow
+type On struct {
+	Push             *Push     `yaml:"push,omitempty"`
+	PullRequest      *struct{} `yaml:"pull_request,omitempty"`
+	WorkflowDispatch *struct{} `yaml:"workflow_dispatch,omitempty"`
+}
+
+type Push struct {
+	Branches []string `yaml:"branches,omitempty"`
+	Tags     []string `yaml:"tags,omitempty"`
+}
+
+type Job struct {
+	Name        string            `yaml:"name,omitempty"`
+	RunsOn      string            `yaml:"runs-on"`
+	Needs       []string          `yaml:"needs,omitempty"`
+	If          string            `yaml:"if,omitempty"`
+	Permissions map[string]string `yaml:"permissions,omitempty"`
+	Strategy    *Strategy         `yaml:"strategy,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+	Steps       []*Step           `yaml:"steps"`
+}
+
+type Strategy struct {
+	FailFast    *bool               `yaml:"fail-fast,omitempty"`
+	MaxParallel int                 `yaml:"max-parallel,omitempty"`
+	Matrix      map[string][]string `yaml:"matrix"`
+}
+
+type Step struct {
+	Name string            `yaml:"name,omitempty"`
+	If   string            `yaml:"if,omitempty"`
+	Uses string            `yaml:"uses,omitempty"`
+	With map[string]string `yaml:"with,omitempty"`
+	Env  map[string]string `yaml:"env,omitempty"`
+	Run  string            `yaml:"run,omitempty"`
+}
+
+// New returns a Workflow triggered by pushes to the default branch, v* tags & pull requests.
+// Other branches are only built by their pull requests so they are not built twice.
+func New(name, branch string) *Workflow {
+	return &Workflow{
+		Name: name,
+		On: On{
+			Push: &Push{
+				Branches: []string{branch},
+				Tags:     []string{"v*"},
+			},
+			PullRequest:      &struct{}{},
+			WorkflowDispatch: &struct{}{},
+		},
+	}
+}
+
+// AddJob adds a job to the workflow
+func (w *Workflow) AddJob(id string, job *Job) *Workflow {
+	w.Jobs = append(w.Jobs, yaml.MapItem{Key: id, Value: job})
+	return w
+}
+
+// Job returns a job or nil if it does not exist
+func (w *Workflow) Job(id string) *Job {
+	for _, j := range w.Jobs {
+		if j.Key == id {
+			return j.Value.(*Job)
+		}
+	}
+	return nil
+}
+
+// JobIds returns the ids of each job
+func (w *Workflow) JobIds() []string {
+	var ids []string
+	for _, j := range w.Jobs {
+		ids = append(ids, j.Key.(string))
+	}
+	return ids
+}
+
+// Build returns the workflow as yaml
+func (w *Workflow) Build() (string, error) {
+	b, err := yaml.Marshal(w)
+	if err != nil {
+		return "", err
+	}
+	return "# Generated workflow\n" + string(b), nil
+}
+
+// NewJob returns a job running on a runner
+func NewJob(name, runsOn string) *Job {
+	return &Job{Name: name, RunsOn: runsOn}
+}
+
+// Step adds a step to a job
+func (j *Job) Step(step *Step) *Job {
+	j.Steps = append(j.Steps, step)
+	return j
+}
+
+// Uses adds a step using an action
+func (j *Job) Uses(name, uses string, with map[string]string) *Job {
+	return j.Step(&Step{Name: name, Uses: uses, With: with})
+}
+
+// Run adds a step running a command
+func (j *Job) Run(name, run string) *Job {
+	return j.Step(&Step{Name: name, Run: run})
+}
+
+// Checkout adds the steps to checkout the repository and install go using the version in go.mod
+func (j *Job) Checkout() *Job {
+	return j.Uses("Checkout", "actions/checkout@v4", map[string]string{"fetch-depth": "0"}).
+		Uses("Setup go", "actions/setup-go@v5", map[string]string{"go-version-file": "go.mod"})
+}
@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWorkflow_Build(t *testing.T) {
+	w := New("Build", "main")
+
+	w.AddJob("test", NewJob("Test", "ubuntu-latest").
+		Checkout().
+		Run("Test", "make test"))
+
+	failFast := false
+	build := NewJob("Build ${{ matrix.platform }}", "ubuntu-latest").
+		Checkout().
+		Run("Build", "make -f Makefile.gen ${{ matrix.platform }}")
+	build.Needs = []string{"test"}
+	build.Strategy = &Strategy{
+		FailFast: &failFast,
+		Matrix:   map[string][]string{"platform": {"linux_amd64", "linux_arm64"}},
+	}
+	w.AddJob("build", build)
+
+	want := `# Generated workflow
+name: Build
+"on":
+  push:
+    branches:
+    - main
+    tags:
+    - v*
+  pull_request: {}
+  workflow_dispatch: {}
+jobs:
+  test:
+    name: Test
+    runs-on: ubuntu-latest
+    steps:
+    - name: Checkout
+      uses: actions/checkout@v4
+      with:
+        fetch-depth: "0"
+    - name: Setup go
+      uses: actions/setup-go@v5
+      with:
+        go-version-file: go.mod
+    - name: Test
+      run: make test
+  build:
+    name: Build ${{ matrix.platform }}
+    runs-on: ubuntu-latest
+    needs:
+    - test
+    strategy:
+      fail-fast: false
+      matrix:
+        platform:
+        - linux_amd64
+        - linux_arm64
+    steps:
+    - name: Checkout
+      uses: actions/checkout@v4
+      with:
+        fetch-depth: "0"
+    - name: Setup go
+      uses: actions/setup-go@v5
+      with:
+        go-version-file: go.mod
+    - name: Build
+      run: make -f Makefile.gen ${{ matrix.platform }}
+`
+	got, err := w.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Build() =\n%s\nwant\n%s", got, want)
+	}
+
+	if ids := w.JobIds(); !slices.Equal(ids, []string{"test", "build"}) {
+		t.Errorf("JobIds() = %v", ids)
+	}
+	if w.Job("build") != build || w.Job("missing") != nil {
+		t.Errorf("Job() returned the wrong job")
+	}
+}